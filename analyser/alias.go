@@ -0,0 +1,70 @@
+package analyser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+)
+
+// LoadAliases load a player alias file mapping SteamID64 of players to their
+// canonical names. The file is a csv file with steam_id and name columns,
+// an optional header row is skipped.
+func LoadAliases(path string) (map[int64]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	aliases := make(map[int64]string)
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		steamID, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+		if err != nil {
+			// header row
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%s:%d: invalid steam id %q", path, line, record[0])
+		}
+		aliases[steamID] = strings.TrimSpace(record[1])
+	}
+
+	return aliases, nil
+}
+
+// SetAliases set canonical names of players by their SteamID64
+func (analyser *Analyser) SetAliases(aliases map[int64]string) { analyser.aliases = aliases }
+
+// playerName get the canonical name of a player. If the player has no alias,
+// the first name seen in the match is used, so that a player reconnecting
+// or renaming under a different name keeps a single identity.
+func (analyser *Analyser) playerName(pplayer *common.PPlayer) string {
+	if name, ok := analyser.aliases[pplayer.SteamID]; ok {
+		return name
+	}
+	// bots do not have a steam id
+	if pplayer.SteamID == 0 {
+		return pplayer.Name
+	}
+	name, ok := analyser.playerNames[pplayer.SteamID]
+	if !ok {
+		name = pplayer.Name
+		analyser.playerNames[pplayer.SteamID] = name
+	}
+
+	return name
+}
@@ -0,0 +1,53 @@
+package analyser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadAliases test that header row is skipped and invalid steam ids are reported
+func TestLoadAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aliases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		content  string
+		expected map[int64]string
+		err      string
+	}{
+		{"steam_id,name\n76561197987713664, s1mple\n2,b\n", map[int64]string{76561197987713664: "s1mple", 2: "b"}, ""},
+		{"1,a\n2,b\n", map[int64]string{1: "a", 2: "b"}, ""},
+		{"steam_id,name\n1,a\nplayer,b\n", nil, ":3: invalid steam id"},
+		{"1,a\n2\n", nil, "wrong number of fields"},
+	}
+	for i, c := range cases {
+		path := filepath.Join(dir, "aliases.csv")
+		if err := ioutil.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		aliases, err := LoadAliases(path)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("case %d: expected error %q, got %v", i, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if len(aliases) != len(c.expected) {
+			t.Errorf("case %d: expected %v, got %v", i, c.expected, aliases)
+		}
+		for steamID, name := range c.expected {
+			if aliases[steamID] != name {
+				t.Errorf("case %d: expected %q for %d, got %q", i, name, steamID, aliases[steamID])
+			}
+		}
+	}
+}
@@ -2,8 +2,14 @@
 package analyser
 
 import (
-	"bytes"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
+	"os"
 
 	"github.com/gogo/protobuf/proto"
 	dem "github.com/markus-wa/demoinfocs-golang"
@@ -16,15 +22,6 @@ import (
 	"github.com/spf13/viper"
 )
 
-// ########### Constants #######################
-// csgo match constants
-const (
-	maxRounds           = 30
-	normalTimeWinRounds = maxRounds/2 + 1
-)
-
-// ############################################
-
 // ############# structs ######################
 
 // Analyser main struct to analyse events
@@ -40,8 +37,8 @@ type Analyser struct {
 	ctScore int
 	// played round number
 	roundPlayed int
-	// number of rounds will be played as overtime
-	NumOvertime int
+	// rules of the match format
+	rules MatchRules
 	// flags
 	// match started flag
 	matchStarted bool
@@ -124,6 +121,15 @@ type Analyser struct {
 	isCTFirstKill bool
 	// Truth value for a weapon fired for this round
 	isWeaponFired bool
+	// Truth value for a weapon other than knife used in the round
+	// for the first parse, used to detect knife rounds
+	isOtherWeaponUsed bool
+	// highest equipment value of players at freeze time end
+	// for the first parse, used to detect knife rounds
+	maxEquipmentValue int
+	isEquipmentSet    bool
+	// reason of cancelling the round, empty if it is not known
+	cancelReason string
 	// Truth value to check an event occured during a round
 	isEventHappened bool
 	// Sometimes, several player has missed the match start but
@@ -135,6 +141,7 @@ type Analyser struct {
 
 	// ******** parser related vars ***
 	// header related information
+	header  p_common.DemoHeader
 	mapName string
 	// metadata of Header
 	mapMetadata *metadata.Map
@@ -146,13 +153,24 @@ type Analyser struct {
 	parser *dem.Parser
 	// logger (converted struct var for concurent logging)
 	log *logging.Logger
-
-	// demo file stream
-	demostream io.Reader
-	// output path for stat writing
-	outPath string
-	// buffer
-	buf *bytes.Buffer
+	// log file of the logger
+	logFile io.Closer
+
+	// demo file stream for the second parsing, it is either
+	// given stream itself if it is seekable or a spool file
+	replayStream io.ReadSeeker
+	// offset of demo start in replay stream
+	replayOffset int64
+	// temp file spooling non seekable demo stream
+	spoolFile *os.File
+	// demo stream read by the first parsing
+	demoStream io.Reader
+	// path of round ledger sidecar file, empty if ledger is not cached
+	ledgerPath string
+	// hash of the demo content read so far
+	hasher hash.Hash
+	// content hash of the demo, it is also the id of the match
+	demoHash string
 	// config of parser
 	cfg dem.ParserConfig
 	// navigator Object
@@ -162,9 +180,26 @@ type Analyser struct {
 	// ******* parsing related vars *******
 	// first parser flag
 	isFirstParse bool
+	// flag indicating the demo is analysed in a single parsing
+	// by staging statistics of each round until its validity is known
+	isSinglePass bool
+	// flag indicating statistics of a round are being staged
+	isRoundStaged bool
+	// flag indicating the staged round has a valid end
+	isStagedRoundValid bool
+	// number of kill positions before the staged round
+	stagedKillPositions int
 	// flag indicating whole analyze finished and
-	// written to disk as a test file
+	// match result has been created
 	isSuccesfulAnalyzed bool
+	// result of the analyze
+	result *MatchResult
+	// handler called when the analyze progressed
+	progressHandler ProgressHandler
+	// last progress reported to the handler
+	lastProgress Progress
+	// first error occured during parsing
+	err error
 	// store round start tick
 	roundStart int
 	// store round end tick
@@ -176,10 +211,49 @@ type Analyser struct {
 	validRounds map[int]*common.RoundTuples
 	// current valid round tuple
 	curValidRound *common.RoundTuples
+	// cvars set by the server
+	cvars map[string]string
 	// ***********************************************
 	// ****** kill positions *************************
 	killPositions []*common.KillPosition
 	roundWinners  map[int]string
+	// number of rounds played reported by the game at the last round start
+	gameRoundPlayed int
+	// number of rounds played reported by the game at the start of each valid round
+	gameRounds map[int]int
+	// number of rounds cancelled on the first parsing
+	cancelledRounds int
+	// rounds and warmup phases excluded on the first parsing
+	excludedRounds []ExcludedRound
+	// start tick of the current warmup phase
+	warmupStart int
+	// path of the round override file
+	overridePath string
+	// start ticks of rounds included by the override file
+	includedRounds map[int]bool
+	// pauses of the match recorded on the first parsing
+	pauses []Pause
+	// ongoing pauses by their kinds and sides
+	activePauses map[string]*Pause
+	// raw statistics of players for each valid round
+	roundPlayers []RoundPlayerResult
+	// canonical names of players by steam id
+	aliases map[int64]string
+	// first seen names of players by steam id
+	playerNames map[int64]string
+	// round statistics of teams by their starting side
+	teamRounds map[p_common.Team]*teamRounds
+	// flag indicating timeline of the match is recorded
+	isTimelineEnabled bool
+	// flag indicating raw feature values and their denominators are
+	// added next to normalised feature values
+	isRawCounts bool
+	// flag indicating freeze time end events are found, so rounds participated are counted
+	isParticipationCounted bool
+	// timeline of valid rounds
+	timeline []TimelineEvent
+	// timeline of the current round, added to the match timeline at the end of the round
+	roundTimeline []TimelineEvent
 
 	// ***********************************************
 	// scheduler for custom events
@@ -196,7 +270,7 @@ type Analyser struct {
 // ######## public interface #######################
 
 // NewAnalyser constructer for getting an analyser
-func NewAnalyser(demostream io.Reader, logPath, outPath string, ismethodname, multiplewriter bool) *Analyser {
+func NewAnalyser(demostream io.Reader, logPath string, ismethodname, multiplewriter bool) (*Analyser, error) {
 	// Configure parsing of ConVar net-message (id=6)
 	cfg := dem.DefaultParserConfig
 	cfg.AdditionalNetMessageCreators = map[int]dem.NetMessageCreator{
@@ -204,38 +278,85 @@ func NewAnalyser(demostream io.Reader, logPath, outPath string, ismethodname, mu
 			return new(msg.CNETMsg_SetConVar)
 		},
 	}
-	var buf bytes.Buffer
-	// buffer demo file for second parsing
-	demostream = io.TeeReader(demostream, &buf)
-
-	parser := dem.NewParserWithConfig(demostream, cfg)
 
-	analyser := &Analyser{parser: parser}
-	analyser.buf = &buf
-	analyser.cfg = cfg
-	analyser.outPath = outPath
+	analyser := &Analyser{cfg: cfg}
 	logLevel := viper.GetString("log.log_level")
-	analyser.log = utils.InitLogger(logPath, logLevel, ismethodname, multiplewriter)
+	logger, logFile, err := utils.InitLogger(logPath, logLevel, ismethodname, multiplewriter)
+	if err != nil {
+		return nil, err
+	}
+	analyser.log = logger
+	analyser.logFile = logFile
+
+	// single pass analyze does not need to read the demo again
+	analyser.isSinglePass = viper.GetBool("parse.single_pass")
+	analyser.isTimelineEnabled = viper.GetBool("output.timeline")
+	analyser.isRawCounts = viper.GetBool("output.raw_counts")
+
+	// if demo stream is seekable, second parsing seek back to the
+	// current offset, otherwise demo is spooled to a temp file
+	if analyser.isSinglePass {
+		analyser.log.Info("Demo will be analyzed in a single pass")
+	} else if rs, ok := demostream.(io.ReadSeeker); ok {
+		if offset, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			analyser.replayStream = rs
+			analyser.replayOffset = offset
+		}
+	}
+	if analyser.replayStream == nil && !analyser.isSinglePass {
+		spoolFile, err := ioutil.TempFile("", "demoanalyzer-*.dem")
+		if err != nil {
+			analyser.Close()
+			return nil, err
+		}
+		analyser.log.WithFields(logging.Fields{
+			"path": spoolFile.Name(),
+		}).Info("Demo stream is not seekable, spooling to temp file")
+		analyser.spoolFile = spoolFile
+		analyser.replayStream = spoolFile
+		demostream = io.TeeReader(demostream, spoolFile)
+	}
+
+	// content of the demo is hashed while it is read
+	analyser.hasher = sha256.New()
+	analyser.demoStream = io.TeeReader(demostream, analyser.hasher)
+	analyser.parser = dem.NewParserWithConfig(bufio.NewReader(analyser.demoStream), cfg)
 
 	analyser.log.Info("Analyser has been created")
 
 	// create map to store valid rounds
 	analyser.validRounds = make(map[int]*common.RoundTuples)
+	analyser.cvars = make(map[string]string)
+	// rules are set from cvars in the first pass and kept for the second pass
+	analyser.rules = newMatchRules()
+	analyser.activePauses = make(map[string]*Pause)
+	analyser.includedRounds = make(map[int]bool)
 
 	analyser.resetAnalyserVars()
 	// init alg related const. vars
 	analyser.initAlgVars()
 
-	return analyser
+	return analyser, nil
+}
 
+// Close remove the spool file and release the log file of the analyser.
+// It has to be called when the analyser is not used anymore, even if
+// the demo has not been analysed.
+func (analyser *Analyser) Close() error {
+	analyser.removeSpoolFile()
+
+	return analyser.logFile.Close()
 }
 
 // handleHeader handle header information an initilize related variables
-func (analyser *Analyser) handleHeader() {
+func (analyser *Analyser) handleHeader() error {
 	analyser.log.Info("Parsing header of demo file")
 	// Parse header
 	header, err := analyser.parser.ParseHeader()
-	utils.CheckError(err)
+	if err != nil {
+		return newAnalyzeError(ErrHeaderUnreadable, err)
+	}
+	analyser.header = header
 	analyser.mapName = header.MapName
 	var tickrate float64
 	if tickrate = header.TickRate(); tickrate == 0 {
@@ -255,26 +376,123 @@ func (analyser *Analyser) handleHeader() {
 	remaningTickCheck := common.SecondsToTick(float64(remaningSeckCheck), analyser.tickRate)
 	analyser.remaningTickCheck = int(remaningTickCheck)
 
+	return nil
 }
 
-// Analyze parse demofile
-func (analyser *Analyser) Analyze() {
+// Analyze parse demofile and return the result of the match
+func (analyser *Analyser) Analyze(ctx context.Context) (*MatchResult, error) {
+	defer analyser.removeSpoolFile()
+	// round ledger of the demo has to be looked up before parsing
+	var ledger *roundLedger
+	if analyser.ledgerPath != "" && !analyser.isSinglePass {
+		var err error
+		if ledger, err = analyser.loadLedger(); err != nil {
+			return nil, err
+		}
+	}
 	// first handle parser header
-	analyser.handleHeader()
+	if err := analyser.handleHeader(); err != nil {
+		return nil, err
+	}
 	// create scheduler for custom events
 	analyser.customScheduler = NewScheduler(analyser, analyser.tickRate)
+	if analyser.isSinglePass {
+		return analyser.analyzeSinglePass(ctx)
+	}
+
+	if ledger != nil {
+		analyser.applyLedger(ledger)
+	} else if err := analyser.analyzeFirstPass(ctx); err != nil {
+		return nil, err
+	}
+	if analyser.overridePath != "" {
+		override, err := analyser.loadOverride()
+		if err != nil {
+			return nil, err
+		} else if override != nil {
+			analyser.applyOverride(override)
+		}
+	}
+	if len(analyser.validRounds) == 0 {
+		return nil, newAnalyzeError(ErrNoValidRounds, nil)
+	}
+
+	analyser.log.Info("Analyzing second time")
+	analyser.isFirstParse = false
+	if err := analyser.initMapData(); err != nil {
+		return nil, err
+	}
+
+	if err := analyser.resetAnalyser(); err != nil {
+		return nil, err
+	}
+	analyser.registerMatchEventHandlers()
+	analyser.registerPlayerEventHandlers()
+	tick, _ := analyser.getGameTick()
+
+	// beacuse there is no event counting as match start on
+	// second parsing, we are calling reset match vars externally
+	// on the beginning of second parser
+	analyser.resetMatchVars(tick)
+
+	// parse frame by frame so that the analyze can be cancelled
+	err := analyser.parseFrames(ctx, SecondPass)
+
+	// sometimes demo files enden unexpectedly however, it is not important
+	// if we already finished the analyze
+	if err == dem.ErrUnexpectedEndOfDemo && analyser.isSuccesfulAnalyzed {
+		analyser.log.Info("Demo file ended unexpectedly however, analze has been finished")
+	} else if err != nil {
+		return nil, analyser.parseError(err)
+	}
+
+	if analyser.err != nil {
+		return nil, analyser.err
+	}
+	if !analyser.isSuccesfulAnalyzed {
+		return nil, newAnalyzeError(ErrMatchNotFinished,
+			fmt.Errorf("%d of %d valid rounds have been played", analyser.roundPlayed, len(analyser.validRounds)))
+	}
+
+	return analyser.result, nil
+}
+
+// analyzeFirstPass parse demofile to find valid rounds
+func (analyser *Analyser) analyzeFirstPass(ctx context.Context) error {
 	analyser.log.Info("Analyzing first time")
 	analyser.isFirstParse = true
 	analyser.registerNetMessageHandlers()
 	analyser.registerMatchEventHandlers()
 	analyser.registerFirstPlayerEventHandlers()
 
-	for hasMoreFrames, err := true, error(nil); hasMoreFrames; hasMoreFrames, err = analyser.parser.ParseNextFrame() {
-		utils.CheckError(err)
+	// sometimes demo files enden unexpectedly however, it is not
+	// important if the match has already ended
+	if err := analyser.parseFrames(ctx, FirstPass); err == dem.ErrUnexpectedEndOfDemo && analyser.matchEnded {
+		analyser.log.Info("Demo file ended unexpectedly however, match has been finished")
+	} else if err != nil {
+		return analyser.parseError(err)
 	}
 
-	analyser.log.Info("Analyzing second time")
-	analyser.isFirstParse = false
+	if analyser.err != nil {
+		return analyser.err
+	}
+	// a demo cut off before the end of the match has only a part of the rounds
+	if !analyser.matchEnded && len(analyser.validRounds) > 0 {
+		return newAnalyzeError(ErrMatchNotFinished,
+			fmt.Errorf("demo ended after %d rounds, score %d-%d", analyser.roundPlayed, analyser.tScore, analyser.ctScore))
+	}
+	if err := analyser.hashDemo(); err != nil {
+		return newAnalyzeError(ErrDemoCorrupted, err)
+	}
+	if analyser.ledgerPath != "" {
+		analyser.saveLedger()
+	}
+
+	return nil
+}
+
+// initMapData initilize map metadata and navigator of the map
+func (analyser *Analyser) initMapData() error {
 	// get map metadata for plotting
 	if mapMetadata, ok := metadata.MapNameToMap[analyser.mapName]; ok {
 		analyser.log.WithFields(logging.Fields{
@@ -299,32 +517,66 @@ func (analyser *Analyser) Analyze() {
 	} else {
 		analyser.log.WithFields(logging.Fields{
 			"map name": analyser.mapName,
+			"err":      err,
 		}).Error("Navigator object has not been initilized.")
 		analyser.navigator = nil
+		// map control features can not be calculated without nav mesh
+		if viper.GetBool("algorithm.require_navmesh") {
+			return newAnalyzeError(ErrNavMeshMissing, err)
+		}
 	}
 
-	analyser.resetAnalyser()
-	analyser.registerMatchEventHandlers()
-	analyser.registerPlayerEventHandlers()
-	tick, _ := analyser.getGameTick()
+	return nil
+}
 
-	// beacuse there is no event counting as match start on
-	// second parsing, we are calling reset match vars externally
-	// on the beginning of second parser
-	analyser.resetMatchVars(tick)
+// parseFrames parse demo frame by frame until the end of the demo.
+// Parsing stops between frames if the context is cancelled or an
+// event handler has reported an error.
+func (analyser *Analyser) parseFrames(ctx context.Context, pass int) error {
+	for hasMoreFrames, err := true, error(nil); hasMoreFrames; hasMoreFrames, err = analyser.parser.ParseNextFrame() {
+		if err != nil {
+			return err
+		}
+		if analyser.err != nil {
+			return analyser.err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		analyser.reportProgress(pass)
+	}
+	analyser.reportProgress(pass)
 
-	// because net messages handler is not registered, we can use
-	// parsetoend, so that we are not protecting sync betwenn net messages
-	// and event dispatch
-	err = analyser.parser.ParseToEnd()
+	return nil
+}
 
-	// sometimes demo files enden unexpectedly however, it is not important
-	// if we already finished the analyze
-	if err == dem.ErrUnexpectedEndOfDemo && analyser.isSuccesfulAnalyzed {
-		analyser.log.Info("Demo file ended unexpectedly however, analze has been finished")
-	} else {
-		utils.CheckError(err)
+// parseError convert an error returned by the parser to an analyze error
+func (analyser *Analyser) parseError(err error) error {
+	// analyze has been cancelled by the caller
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	// parsing has been stopped by an event handler
+	if err == analyser.err {
+		return err
+	}
+
+	return newAnalyzeError(ErrDemoCorrupted, err)
+}
+
+// removeSpoolFile remove temp file used for spooling demo stream
+func (analyser *Analyser) removeSpoolFile() {
+	if analyser.spoolFile == nil {
+		return
+	}
+	analyser.spoolFile.Close()
+	if err := os.Remove(analyser.spoolFile.Name()); err != nil {
+		analyser.log.WithFields(logging.Fields{
+			"path": analyser.spoolFile.Name(),
+			"err":  err,
+		}).Error("Spool file could not be removed")
 	}
+	analyser.spoolFile = nil
 }
 
 // initAlgVars init algorithm related vars by using config file
@@ -4,8 +4,8 @@ import (
 	p_common "github.com/markus-wa/demoinfocs-golang/common"
 	"github.com/markus-wa/demoinfocs-golang/events"
 	common "github.com/quancore/demoanalyzer-go/common"
-	utils "github.com/quancore/demoanalyzer-go/utils"
 	logging "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 // ######## Internal checkers###
@@ -196,18 +196,9 @@ func (analyser *Analyser) checkMatchContinuity(tick int) bool {
 				"team terrorist":     tScore,
 				"team ct terrorist":  ctScore,
 			}).Info("Match is over. ")
-			analyser.notifyAllMatchEnd(tScore, ctScore)
-			analyser.printPlayers()
-			analyser.writeToFile(analyser.outPath)
-			if analyser.mapMetadata != nil {
-				analyser.printHeadmap()
-				analyser.clusterPoints()
+			if !analyser.finishMatch() {
+				return false
 			}
-
-			analyser.isOvertime = false
-			analyser.matchEnded = true
-			// set file succesfully analyzed
-			analyser.isSuccesfulAnalyzed = true
 		} else {
 			analyser.isOvertime = true
 
@@ -217,24 +208,34 @@ func (analyser *Analyser) checkMatchContinuity(tick int) bool {
 	return analyser.isOvertime
 }
 
-// checkMatchEnd check whether match should end for given scores
-func (analyser *Analyser) checkMatchEnd(tScore, ctScore int) (bool, bool) {
-	mpOvertimeMaxrounds := analyser.NumOvertime
-	nOvertimeRounds := ctScore + tScore - maxRounds
-	var matchOver, isovertime bool
-
-	if ((ctScore == normalTimeWinRounds) != (tScore == normalTimeWinRounds)) || nOvertimeRounds >= 0 {
-		// a team won in normal time or at least 30 rounds have been played
-		absDiff := utils.Abs(ctScore - tScore)
-		x := nOvertimeRounds % mpOvertimeMaxrounds
-		nRoundsOfHalf := mpOvertimeMaxrounds / 2
-		if nOvertimeRounds < 0 || ((x == 0 && absDiff == 2) || (x > nRoundsOfHalf && absDiff >= nRoundsOfHalf)) {
-			matchOver = true
+// finishMatch notify players the match end and create the match result
+func (analyser *Analyser) finishMatch() bool {
+	analyser.notifyAllMatchEnd(analyser.tScore, analyser.ctScore)
+	analyser.printPlayers()
+	// if test needed for output
+	if viper.GetBool("checkanalyzer") {
+		if err := analyser.testGameState(); err != nil {
+			analyser.setError(err)
+			return false
+		}
+		if err := analyser.testParticipant(); err != nil {
+			analyser.setError(err)
+			return false
 		}
-		isovertime = true
 	}
+	analyser.result = analyser.buildMatchResult()
+
+	analyser.isOvertime = false
+	analyser.matchEnded = true
+	// set file succesfully analyzed
+	analyser.isSuccesfulAnalyzed = true
 
-	return matchOver, isovertime
+	return true
+}
+
+// checkMatchEnd check whether match should end for given scores
+func (analyser *Analyser) checkMatchEnd(tScore, ctScore int) (bool, bool) {
+	return analyser.rules.isMatchOver(tScore, ctScore)
 }
 
 // checkClutchSituation check alive players for a clutch situation
@@ -363,16 +364,15 @@ func (analyser *Analyser) checkMoneyValidity() bool {
 		return true
 	}
 	// normal time half starts
-	if analyser.roundPlayed == 0 || analyser.roundPlayed == 15 {
+	if analyser.roundPlayed == 0 || analyser.roundPlayed == analyser.rules.halfRounds() {
 		if analyser.currentSMoney != 800 {
 			return false
 		}
-	} else if analyser.roundPlayed >= maxRounds { //overtime
+	} else if analyser.roundPlayed >= analyser.rules.MaxRounds { //overtime
 		ctScore := analyser.ctScore
 		tScore := analyser.tScore
-		mpOvertimeMaxrounds := analyser.NumOvertime
-		nOvertimeRounds := ctScore + tScore - maxRounds
-		nRoundsOfHalf := mpOvertimeMaxrounds / 2
+		nOvertimeRounds := ctScore + tScore - analyser.rules.MaxRounds
+		nRoundsOfHalf := analyser.rules.overtimeHalfRounds()
 		if nOvertimeRounds%nRoundsOfHalf == 0 {
 			if analyser.currentSMoney != 16000 {
 				return false
@@ -386,22 +386,12 @@ func (analyser *Analyser) checkMoneyValidity() bool {
 // checkHalfBreak return true for if we are in half break
 func (analyser *Analyser) checkHalfBreak(tScore, ctScore int) bool {
 	RoundPlayed := ctScore + tScore
-	mpOvertimeMaxrounds := analyser.NumOvertime
-	nOvertimeRounds := RoundPlayed - maxRounds
-	nRoundsOfHalf := mpOvertimeMaxrounds / 2
-
-	// normal time
-	if nOvertimeRounds <= 0 {
-		if RoundPlayed == 15 || (RoundPlayed == 30 && analyser.matchEnded == false) {
-			return true
-		}
-	} else { //overtime
-		if nOvertimeRounds%nRoundsOfHalf == 0 {
-			return true
-		}
+	// there is no break after the last round of normal time if match has ended
+	if RoundPlayed == analyser.rules.MaxRounds && analyser.matchEnded {
+		return false
 	}
 
-	return false
+	return analyser.rules.isHalfStart(RoundPlayed)
 }
 
 // checkPlayerTeamValidity check whether a player is assigned a valid team
@@ -436,4 +426,29 @@ func (analyser *Analyser) checkRoundEventValid(tick int) bool {
 	return false
 }
 
+// isStatPass check whether player statistics are collected on current parsing
+func (analyser *Analyser) isStatPass() bool {
+	return !analyser.isFirstParse || analyser.isSinglePass
+}
+
+// checkStagedEventValid check given event tick belongs to the staged round
+// using for single pass analyze
+func (analyser *Analyser) checkStagedEventValid(tick int) bool {
+	if !analyser.isRoundStaged || tick <= analyser.roundStart {
+		return false
+	}
+
+	// round is ongoing or it has a valid end and waiting for official end
+	return (analyser.inRound && !analyser.isCancelled) || analyser.isStagedRoundValid
+}
+
+// checkScheduledEventValid check a custom event scheduled for given tick can be handled
+func (analyser *Analyser) checkScheduledEventValid(tick int) bool {
+	if analyser.isSinglePass {
+		return analyser.checkStagedEventValid(tick)
+	}
+
+	return analyser.checkRoundEventValid(tick)
+}
+
 // #####################################
@@ -71,7 +71,13 @@ type preCroshairReplecament struct {
 func (ec preCroshairReplecament) handleEvent(tick int) {
 	// first handle first kill event
 	if player, playerOK := ec.analyser.getPlayerByID(ec.killerID, false); playerOK {
-		x, y := player.SetLastYawPitch()
+		var x, y float32
+		// on single pass analyze the event is handled after the tick it is scheduled
+		if ec.analyser.isSinglePass {
+			x, y = player.SetLastYawPitchAt(tick)
+		} else {
+			x, y = player.SetLastYawPitch()
+		}
 		ec.analyser.log.WithFields(logging.Fields{
 			"tick":   tick,
 			"killer": player.Name,
@@ -56,11 +56,14 @@ func NewScheduler(analyser *Analyser, tickRate float64) *Scheduler {
 func (sc *Scheduler) addEvent(currentTick int, offsetSec float64, ev event) int {
 	offsetTick := int(common.SecondsToTick(offsetSec, sc.tickRate))
 	executionTick := currentTick + offsetTick
-	if executionTick > 0 {
-		sc.scheduledTasks[executionTick] = append(sc.scheduledTasks[executionTick], ev)
-		if sc.earliestValidTick > executionTick {
-			sc.earliestValidTick = executionTick
+	// on single pass analyze, an event scheduled to a tick already
+	// parsed can only be handled right now
+	if sc.analyser.isSinglePass && executionTick <= currentTick {
+		if sc.analyser.checkScheduledEventValid(executionTick) {
+			ev.handleEvent(executionTick)
 		}
+	} else {
+		sc.addEventAt(executionTick, ev)
 	}
 
 	sc.analyser.log.WithFields(logging.Fields{
@@ -73,6 +76,16 @@ func (sc *Scheduler) addEvent(currentTick int, offsetSec float64, ev event) int
 	return executionTick
 }
 
+// addEventAt add an event to be handled at given tick
+func (sc *Scheduler) addEventAt(executionTick int, ev event) {
+	if executionTick > 0 {
+		sc.scheduledTasks[executionTick] = append(sc.scheduledTasks[executionTick], ev)
+		if sc.earliestValidTick > executionTick {
+			sc.earliestValidTick = executionTick
+		}
+	}
+}
+
 func (sc *Scheduler) checkEvent(tick int) {
 	if tick >= sc.earliestValidTick {
 		sc.analyser.log.WithFields(logging.Fields{
@@ -81,7 +94,7 @@ func (sc *Scheduler) checkEvent(tick int) {
 		}).Info("Check event called")
 		if eventList, ok := sc.scheduledTasks[tick]; ok {
 			// current round is ongoing, the event is already valid
-			if sc.analyser.checkRoundEventValid(tick) {
+			if sc.analyser.checkScheduledEventValid(tick) {
 				for _, currEvent := range eventList {
 					currEvent.handleEvent(tick)
 					currEvent.reschedule(tick, sc, currEvent)
@@ -93,6 +106,12 @@ func (sc *Scheduler) checkEvent(tick int) {
 	}
 }
 
+// reset drop all scheduled events
+func (sc *Scheduler) reset() {
+	sc.scheduledTasks = make(map[int][]event)
+	sc.earliestValidTick = math.MaxInt32
+}
+
 func (sc Scheduler) getMinKey(taskMap map[int][]event) int {
 	minNumber := math.MaxInt32
 	for k := range taskMap {
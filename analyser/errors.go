@@ -0,0 +1,68 @@
+package analyser
+
+import (
+	"errors"
+	"fmt"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+)
+
+// ########### errors #######################
+
+var (
+	// ErrHeaderUnreadable header of the demo file can not be parsed
+	ErrHeaderUnreadable = errors.New("demo header is unreadable")
+	// ErrDemoCorrupted demo file can not be parsed until the end
+	ErrDemoCorrupted = errors.New("demo file is corrupted")
+	// ErrNoValidRounds no valid round has been found on first parsing
+	ErrNoValidRounds = errors.New("no valid rounds")
+	// ErrMatchNotFinished demo ended before the end of the match
+	ErrMatchNotFinished = errors.New("match is not finished")
+	// ErrInvalidGameState final game state (scores, played rounds) is not consistent
+	ErrInvalidGameState = errors.New("invalid game state")
+	// ErrNotEnoughParticipants a team has less active participant than expected
+	ErrNotEnoughParticipants = errors.New("not enough participants")
+	// ErrBackupRestored a backup has been restored in a single pass analyze,
+	// statistics of the replayed rounds can not be rolled back
+	ErrBackupRestored = errors.New("backup restored in single pass")
+	// ErrNavMeshMissing nav mesh file of the map can not be found or parsed
+	ErrNavMeshMissing = common.ErrNavMeshMissing
+	// ErrUnknownRoundType round type is not one of the known round types
+	ErrUnknownRoundType = common.ErrUnknownRoundType
+)
+
+// AnalyzeError error returned by the analyser.
+// Kind is one of the exported errors so that errors.Is can be used to
+// classify it while the underlying cause is still available.
+type AnalyzeError struct {
+	Kind error
+	Err  error
+}
+
+// newAnalyzeError create an analyze error with given kind and cause
+func newAnalyzeError(kind, err error) *AnalyzeError {
+	return &AnalyzeError{Kind: kind, Err: err}
+}
+
+// Error return error string
+func (e *AnalyzeError) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
+
+// Unwrap return underlying cause
+func (e *AnalyzeError) Unwrap() error { return e.Err }
+
+// Is report whether target is the kind of the error
+func (e *AnalyzeError) Is(target error) bool { return target == e.Kind }
+
+// setError record first error occured in an event handler,
+// parsing stops after the current frame
+func (analyser *Analyser) setError(err error) {
+	if err == nil || analyser.err != nil {
+		return
+	}
+	analyser.err = err
+}
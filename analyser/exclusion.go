@@ -0,0 +1,173 @@
+package analyser
+
+import (
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	"github.com/markus-wa/demoinfocs-golang/events"
+	logging "github.com/sirupsen/logrus"
+)
+
+// reasons of excluding a round from the match
+const (
+	// round has been cancelled by validity checks of the first parsing
+	ReasonInvalid = "invalid"
+	// round event has been called before match start or after match end
+	ReasonOutsideMatch = "outside_match"
+	// round has ended with a surrender or a draw
+	ReasonInvalidEndReason = "invalid_end_reason"
+	// no one has been hurt and bomb has not been planted
+	ReasonNoDamage = "no_damage"
+	// round has ended without a winner team
+	ReasonNoWinner = "no_winner"
+	// score update of the round end is not valid
+	ReasonInvalidScore = "invalid_score"
+	// number of participants is not expected for the match
+	ReasonParticipants = "participants"
+	// start money is not expected for the round
+	ReasonInvalidMoney = "invalid_money"
+	// round has officially ended without a round end
+	ReasonNoRoundEnd = "no_round_end"
+	// only knives have been used in the round
+	ReasonKnifeRound = "knife_round"
+	// round has been played in warmup
+	ReasonWarmup = "warmup"
+)
+
+// price of the default pistol, a knife round is played without it
+const defaultPistolValue = 200
+
+// ExcludedRound a round or a phase of the demo excluded from the match
+type ExcludedRound struct {
+	// number of the round if it had been counted, zero for a warmup phase
+	Round     int `json:"round"`
+	StartTick int `json:"start_tick"`
+	EndTick   int `json:"end_tick"`
+	// official end tick of a round excluded after the first parsing
+	OfficialEndTick int `json:"official_end_tick"`
+	// scores at the end of the round
+	TScore  int    `json:"t_score"`
+	CTScore int    `json:"ct_score"`
+	Reason  string `json:"reason"`
+}
+
+// handleCheckWeapon record weapons used in the round to detect knife rounds
+func (analyser *Analyser) handleCheckWeapon(weapon *p_common.Equipment) {
+	if weapon == nil {
+		return
+	}
+	if weapon.Weapon != p_common.EqKnife && weapon.Weapon != p_common.EqWorld && weapon.Weapon != p_common.EqUnknown {
+		analyser.isOtherWeaponUsed = true
+	}
+}
+
+// handleCheckEquipment record the highest equipment value of players at freeze time end to detect knife rounds
+func (analyser *Analyser) handleCheckEquipment() {
+	analyser.maxEquipmentValue = 0
+	for _, currPlayer := range analyser.parser.GameState().Participants().Playing() {
+		if currPlayer.CurrentEquipmentValue > analyser.maxEquipmentValue {
+			analyser.maxEquipmentValue = currPlayer.CurrentEquipmentValue
+		}
+	}
+	analyser.isEquipmentSet = true
+}
+
+// checkKnifeRound check whether only knives have been used in the round and
+// no player has had an equipment worth a default pistol at freeze time end.
+// A knife kill in a pistol round is not enough to exclude the round.
+func (analyser *Analyser) checkKnifeRound() bool {
+	if analyser.isOtherWeaponUsed || !analyser.isEquipmentSet {
+		return false
+	}
+
+	return analyser.maxEquipmentValue < defaultPistolValue
+}
+
+// roundExclusionReason get the reason of excluding the ending round, empty if it is not excluded
+func (analyser *Analyser) roundExclusionReason(isWarmup bool) string {
+	if isWarmup {
+		return ReasonWarmup
+	}
+	if analyser.checkKnifeRound() {
+		return ReasonKnifeRound
+	}
+
+	return ""
+}
+
+// checkExcludedRound cancel the ending round if it is played in warmup or it is a knife round
+func (analyser *Analyser) checkExcludedRound(tick int) {
+	reason := analyser.roundExclusionReason(analyser.parser.GameState().IsWarmupPeriod())
+	if reason == "" {
+		return
+	}
+
+	analyser.log.WithFields(logging.Fields{
+		"tick":   tick,
+		"reason": reason,
+	}).Error("Round will be excluded.")
+	analyser.cancelRound(reason)
+}
+
+// cancelRound cancel the current round with given reason.
+// Reason of a round cancelled before is kept.
+func (analyser *Analyser) cancelRound(reason string) {
+	if !analyser.isCancelled || analyser.cancelReason == "" {
+		analyser.cancelReason = reason
+	}
+	analyser.isCancelled = true
+}
+
+// excludeRound record the cancelled round ending at given tick as excluded.
+// A round cancelled at many sites is recorded once.
+func (analyser *Analyser) excludeRound(tick int) {
+	reason := analyser.cancelReason
+	if reason == "" {
+		reason = ReasonInvalid
+	}
+	// rounds in warmup are covered by the warmup phase
+	if reason == ReasonWarmup || analyser.parser.GameState().IsWarmupPeriod() {
+		return
+	}
+	// round has already been recorded as a valid or an excluded round
+	if round, ok := analyser.validRounds[analyser.roundPlayed]; ok && round.StartTick == analyser.roundStart {
+		return
+	}
+	if n := len(analyser.excludedRounds); n > 0 && analyser.excludedRounds[n-1].StartTick == analyser.roundStart {
+		return
+	}
+
+	analyser.cancelledRounds++
+	analyser.excludedRounds = append(analyser.excludedRounds, ExcludedRound{
+		Round:     analyser.roundPlayed + 1,
+		StartTick: analyser.roundStart,
+		EndTick:   tick,
+		TScore:    analyser.tScore,
+		CTScore:   analyser.ctScore,
+		Reason:    reason,
+	})
+}
+
+// handleWarmupChange record warmup phases of the demo as excluded
+func (analyser *Analyser) handleWarmupChange(e events.IsWarmupPeriodChanged) {
+	tick, err := analyser.getGameTick()
+	if err || !analyser.isFirstParse {
+		return
+	}
+
+	if e.NewIsWarmupPeriod {
+		analyser.warmupStart = tick
+		analyser.log.WithFields(logging.Fields{
+			"tick": tick,
+		}).Info("Warmup has been started")
+		return
+	}
+
+	analyser.excludedRounds = append(analyser.excludedRounds, ExcludedRound{
+		StartTick: analyser.warmupStart,
+		EndTick:   tick,
+		Reason:    ReasonWarmup,
+	})
+	analyser.log.WithFields(logging.Fields{
+		"tick":       tick,
+		"start tick": analyser.warmupStart,
+	}).Info("Warmup has been ended")
+}
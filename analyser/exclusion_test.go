@@ -0,0 +1,33 @@
+package analyser
+
+import "testing"
+
+// TestRoundExclusionReason test that knife rounds need knife only equipment and warmup rounds are excluded
+func TestRoundExclusionReason(t *testing.T) {
+	cases := []struct {
+		name              string
+		isWarmup          bool
+		isOtherWeaponUsed bool
+		isEquipmentSet    bool
+		maxEquipmentValue int
+		expected          string
+	}{
+		{"knife round", false, false, true, 0, ReasonKnifeRound},
+		{"pistol round with a knife kill", false, false, true, 200, ""},
+		{"pistol round", false, true, true, 850, ""},
+		{"knife equipment with a gun used", false, true, true, 0, ""},
+		{"equipment is not known", false, false, false, 0, ""},
+		{"warmup round", true, true, true, 4700, ReasonWarmup},
+		{"knife round in warmup", true, false, true, 0, ReasonWarmup},
+	}
+	for _, c := range cases {
+		analyser := &Analyser{
+			isOtherWeaponUsed: c.isOtherWeaponUsed,
+			isEquipmentSet:    c.isEquipmentSet,
+			maxEquipmentValue: c.maxEquipmentValue,
+		}
+		if actual := analyser.roundExclusionReason(c.isWarmup); actual != c.expected {
+			t.Errorf("%s: expected reason %q, got %q", c.name, c.expected, actual)
+		}
+	}
+}
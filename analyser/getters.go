@@ -119,6 +119,16 @@ func (analyser *Analyser) getSinglePlayerID(player *p_common.Player, eventName s
 	return playerID, playerOK
 }
 
+// currentRound get number of the round currently played
+func (analyser *Analyser) currentRound() int {
+	// on first parsing, played round number is updated at the end of the round
+	if analyser.isFirstParse && analyser.inRound {
+		return analyser.roundPlayed + 1
+	}
+
+	return analyser.roundPlayed
+}
+
 func (analyser *Analyser) getWinnerTeam() p_common.Team {
 	// get which team won
 	teamWon := p_common.TeamUnassigned
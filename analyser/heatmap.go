@@ -22,13 +22,16 @@ const (
 	epsX        = 40
 	epsY        = 40
 	minpoints   = 4
-	outImg      = "heatmap.jpg"
-	clusterOut  = "cluster.png"
+	// HeatmapOut default path of kill heatmap image
+	HeatmapOut = "heatmap.jpg"
+	// ClusterOut default path of kill cluster image
+	ClusterOut = "cluster.png"
 )
 
-func (analyser *Analyser) printHeadmap() {
+// PrintHeatmap draw kill positions of a match result as a heatmap on map radar image
+func PrintHeatmap(result *MatchResult, outPath string) error {
 	var positions []r2.Point
-	for _, position := range analyser.killPositions {
+	for _, position := range result.KillPositions {
 		positions = append(positions, position.KillPoint)
 	}
 	r2Bounds := r2.RectFromPoints(positions...)
@@ -46,10 +49,15 @@ func (analyser *Analyser) printHeadmap() {
 
 	// read base radar map
 	gopath := utils.GetGoPath()
-	fMap, err := os.Open(fmt.Sprintf("%s/src/github.com/markus-wa/demoinfocs-golang/metadata/maps/%s.jpg", gopath, analyser.mapName))
-	utils.CheckError(err)
+	fMap, err := os.Open(fmt.Sprintf("%s/src/github.com/markus-wa/demoinfocs-golang/metadata/maps/%s.jpg", gopath, result.Match.MapName))
+	if err != nil {
+		return err
+	}
+	defer fMap.Close()
 	imgMap, _, err := image.Decode(fMap)
-	utils.CheckError(err)
+	if err != nil {
+		return err
+	}
 
 	// Create output canvas and use map overview image as base
 	img := image.NewRGBA(imgMap.Bounds())
@@ -60,22 +68,22 @@ func (analyser *Analyser) printHeadmap() {
 	draw.Draw(img, bounds, imgHeatmap, image.ZP, draw.Over)
 
 	// Write to stdout
-	f, err := os.Create(outImg)
+	f, err := os.Create(outPath)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer f.Close()
-	err = jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
-	utils.CheckError(err)
 
+	return jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
 }
 
-func (analyser *Analyser) clusterPoints() {
+// ClusterPoints cluster kill positions of a match result and plot them on map radar image
+func ClusterPoints(result *MatchResult, outPath string) error {
 	var positions []r2.Point
 	// var clusterPositions cluster.PointList
 	var clusterPositions point.Points
 
-	for _, position := range analyser.killPositions {
+	for _, position := range result.KillPositions {
 		positions = append(positions, position.KillPoint)
 		newPoint := point.New(position.KillPoint.X, position.KillPoint.Y*-1)
 		// clusterPositions = append(clusterPositions, cluster.Point{position.KillPoint.X, position.KillPoint.Y})
@@ -91,6 +99,6 @@ func (analyser *Analyser) clusterPoints() {
 	// clusters, noise := cluster.DBScan(clusterPositions, 0.08, 10) // eps is 800m, 10 points minimum in eps-neighborhood
 	clusters := cluster.Search(clusterPositions, []float64{epsX, epsY}, minpoints)
 	plotter := SimplePlotter{}
-	plotter.Plot(clusterOut, analyser.mapName, bounds, clusters)
 
+	return plotter.Plot(outPath, result.Match.MapName, bounds, clusters)
 }
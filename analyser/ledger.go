@@ -0,0 +1,252 @@
+package analyser
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// The round ledger is the result of the first parsing of a demo. It is
+// cached to a sidecar file so that the first parsing can be skipped when
+// the same demo is analysed again with the same analyzer version and
+// the same match rule overrides.
+
+// LedgerExt extension of round ledger sidecar file
+const LedgerExt = ".ledger.json"
+
+// kinds of custom events stored in a ledger
+const (
+	crosshairEventKind = "crosshair"
+	firstKillEventKind = "first_kill"
+)
+
+// roundLedger result of the first parsing of a demo
+type roundLedger struct {
+	DemoHash        string                      `json:"demo_hash"`
+	AnalyzerVersion string                      `json:"analyzer_version"`
+	RuleOverrides   string                      `json:"rule_overrides"`
+	ValidRounds     map[int]*common.RoundTuples `json:"valid_rounds"`
+	Cvars           map[string]string           `json:"cvars"`
+	CancelledRounds int                         `json:"cancelled_rounds"`
+	ExcludedRounds  []ExcludedRound             `json:"excluded_rounds"`
+	Pauses          []Pause                     `json:"pauses"`
+	Events          []ledgerEvent               `json:"events"`
+}
+
+// ledgerEvent custom event scheduled on the first parsing
+type ledgerEvent struct {
+	Kind     string `json:"kind"`
+	Tick     int    `json:"tick"`
+	KillerID int64  `json:"killer_id"`
+}
+
+// ledgerFile content of a sidecar file. An archive can include many
+// demos so a sidecar file can store ledgers of many demos.
+type ledgerFile struct {
+	Ledgers []*roundLedger `json:"ledgers"`
+}
+
+// LedgerPath get path of the round ledger sidecar file of a demo file
+func LedgerPath(demoPath string) string { return demoPath + LedgerExt }
+
+// SetLedgerPath set path of the round ledger sidecar file. If it is set, the
+// first parsing is skipped when a ledger of the demo exists in the file,
+// otherwise the ledger is written to the file after the first parsing.
+func (analyser *Analyser) SetLedgerPath(path string) { analyser.ledgerPath = path }
+
+// hashDemo compute content hash of the demo by reading the rest of the demo stream
+func (analyser *Analyser) hashDemo() error {
+	if analyser.demoHash != "" {
+		return nil
+	}
+	// a non seekable demo stream is spooled while it is hashed
+	if _, err := io.Copy(ioutil.Discard, analyser.demoStream); err != nil {
+		return err
+	}
+	analyser.demoHash = hex.EncodeToString(analyser.hasher.Sum(nil))
+
+	return nil
+}
+
+// loadLedger get the ledger of the demo from sidecar file, nil if there is no valid ledger
+func (analyser *Analyser) loadLedger() (*roundLedger, error) {
+	file, err := readLedgerFile(analyser.ledgerPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			analyser.log.WithFields(logging.Fields{
+				"path": analyser.ledgerPath,
+				"err":  err,
+			}).Error("Round ledger could not be read")
+		}
+		return nil, nil
+	}
+
+	candidates := file.candidates(viper.GetString("output.analyzer_version"), ruleOverrides())
+	// demo is hashed while it is read by the first parsing,
+	// it is only read in advance if there is a ledger to compare
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	if err := analyser.hashDemo(); err != nil {
+		return nil, newAnalyzeError(ErrDemoCorrupted, err)
+	}
+	if err := analyser.resetParser(); err != nil {
+		return nil, newAnalyzeError(ErrDemoCorrupted, err)
+	}
+
+	ledger := findLedger(candidates, analyser.demoHash)
+	if ledger != nil {
+		analyser.log.WithFields(logging.Fields{
+			"path":         analyser.ledgerPath,
+			"valid rounds": len(ledger.ValidRounds),
+		}).Info("Round ledger has been found, first parsing is skipped")
+	}
+
+	return ledger, nil
+}
+
+// candidates get ledgers written by given analyzer version with given rule overrides
+func (file *ledgerFile) candidates(version, overrides string) []*roundLedger {
+	var candidates []*roundLedger
+	for _, ledger := range file.Ledgers {
+		if ledger.AnalyzerVersion == version && ledger.RuleOverrides == overrides {
+			candidates = append(candidates, ledger)
+		}
+	}
+
+	return candidates
+}
+
+// findLedger get the ledger of a demo with given hash, nil if there is not any
+func findLedger(ledgers []*roundLedger, demoHash string) *roundLedger {
+	for _, ledger := range ledgers {
+		if ledger.DemoHash == demoHash {
+			return ledger
+		}
+	}
+
+	return nil
+}
+
+// put add a ledger to the file by replacing the previous ledger of the same demo
+func (file *ledgerFile) put(ledger *roundLedger) {
+	var ledgers []*roundLedger
+	for _, other := range file.Ledgers {
+		if other.DemoHash != ledger.DemoHash {
+			ledgers = append(ledgers, other)
+		}
+	}
+	file.Ledgers = append(ledgers, ledger)
+}
+
+// applyLedger restore the state of the analyser after the first parsing from a ledger
+func (analyser *Analyser) applyLedger(ledger *roundLedger) {
+	for roundNumber, round := range ledger.ValidRounds {
+		analyser.validRounds[roundNumber] = round
+	}
+	for name, value := range ledger.Cvars {
+		analyser.setConVar(name, value)
+	}
+	analyser.cancelledRounds = ledger.CancelledRounds
+	analyser.excludedRounds = ledger.ExcludedRounds
+	analyser.pauses = ledger.Pauses
+	for _, ev := range ledger.Events {
+		evCommon := eventCommon{analyser: analyser, isPeriodic: false}
+		switch ev.Kind {
+		case crosshairEventKind:
+			evCommon.offsetSec = -analyser.beforeCrosshair
+			analyser.customScheduler.addEventAt(ev.Tick, preCroshairReplecament{eventCommon: evCommon, killerID: ev.KillerID})
+		case firstKillEventKind:
+			evCommon.offsetSec = analyser.afterFirstKill
+			analyser.customScheduler.addEventAt(ev.Tick, postFirstKillChecker{eventCommon: evCommon, killerID: ev.KillerID})
+		}
+	}
+}
+
+// saveLedger write the ledger of the first parsing to sidecar file
+func (analyser *Analyser) saveLedger() {
+	ledger := &roundLedger{
+		DemoHash:        analyser.demoHash,
+		AnalyzerVersion: viper.GetString("output.analyzer_version"),
+		RuleOverrides:   ruleOverrides(),
+		ValidRounds:     analyser.validRounds,
+		Cvars:           analyser.cvars,
+		CancelledRounds: analyser.cancelledRounds,
+		ExcludedRounds:  analyser.excludedRounds,
+		Pauses:          analyser.pauses,
+	}
+	for tick, eventList := range analyser.customScheduler.scheduledTasks {
+		for _, currEvent := range eventList {
+			switch ev := currEvent.(type) {
+			case preCroshairReplecament:
+				ledger.Events = append(ledger.Events, ledgerEvent{Kind: crosshairEventKind, Tick: tick, KillerID: ev.killerID})
+			case postFirstKillChecker:
+				ledger.Events = append(ledger.Events, ledgerEvent{Kind: firstKillEventKind, Tick: tick, KillerID: ev.killerID})
+			}
+		}
+	}
+	sort.SliceStable(ledger.Events, func(i, j int) bool { return ledger.Events[i].Tick < ledger.Events[j].Tick })
+
+	// keep ledgers of other demos in the same file
+	file, err := readLedgerFile(analyser.ledgerPath)
+	if err != nil {
+		file = &ledgerFile{}
+	}
+	file.put(ledger)
+
+	if err := writeLedgerFile(analyser.ledgerPath, file); err != nil {
+		analyser.log.WithFields(logging.Fields{
+			"path": analyser.ledgerPath,
+			"err":  err,
+		}).Error("Round ledger could not be written")
+		return
+	}
+	analyser.log.WithFields(logging.Fields{
+		"path": analyser.ledgerPath,
+	}).Info("Round ledger has been written")
+}
+
+// readLedgerFile read a ledger sidecar file
+func readLedgerFile(path string) (*ledgerFile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &ledgerFile{}
+	if err := json.Unmarshal(content, file); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// writeLedgerFile write a ledger sidecar file by replacing it at once
+func writeLedgerFile(path string, file *ledgerFile) error {
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
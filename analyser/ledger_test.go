@@ -0,0 +1,96 @@
+package analyser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+)
+
+// TestLedgerFile test that ledgers of many demos of an archive share a sidecar file and
+// a ledger is only found for the same demo, analyzer version and rule overrides
+func TestLedgerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := LedgerPath(filepath.Join(dir, "archive.zip"))
+
+	first := &roundLedger{
+		DemoHash:        "aa",
+		AnalyzerVersion: "1.0",
+		ValidRounds:     map[int]*common.RoundTuples{1: {StartTick: 100, EndTick: 140, OfficialEndTick: 145, TScore: 1}},
+		Cvars:           map[string]string{"mp_maxrounds": "30"},
+		CancelledRounds: 1,
+		ExcludedRounds:  []ExcludedRound{{StartTick: 10, EndTick: 90, Reason: ReasonWarmup}},
+		Events:          []ledgerEvent{{Kind: crosshairEventKind, Tick: 120, KillerID: 7}},
+	}
+	second := &roundLedger{
+		DemoHash:        "bb",
+		AnalyzerVersion: "1.0",
+		ValidRounds:     map[int]*common.RoundTuples{1: {StartTick: 200, EndTick: 240, OfficialEndTick: 245, CTScore: 1}},
+		Cvars:           map[string]string{},
+	}
+	// two entries of one archive are written one after another
+	for _, ledger := range []*roundLedger{first, second} {
+		file, err := readLedgerFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			file = &ledgerFile{}
+		}
+		file.put(ledger)
+		if err := writeLedgerFile(path, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := readLedgerFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Ledgers) != 2 {
+		t.Fatalf("expected 2 ledgers, got %d", len(file.Ledgers))
+	}
+	for _, expected := range []*roundLedger{first, second} {
+		actual := findLedger(file.candidates("1.0", ""), expected.DemoHash)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected ledger %+v, got %+v", expected, actual)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		hash      string
+		version   string
+		overrides string
+	}{
+		{"hash mismatch", "cc", "1.0", ""},
+		{"version bump", "aa", "1.1", ""},
+		{"rule override mismatch", "aa", "1.0", "mp_maxrounds=24"},
+	}
+	for _, c := range cases {
+		if ledger := findLedger(file.candidates(c.version, c.overrides), c.hash); ledger != nil {
+			t.Errorf("%s: expected no ledger, got %+v", c.name, ledger)
+		}
+	}
+
+	// ledger of a demo analysed again replaces its previous ledger only
+	updated := *first
+	updated.CancelledRounds = 2
+	file.put(&updated)
+	if len(file.Ledgers) != 2 {
+		t.Fatalf("expected 2 ledgers after update, got %d", len(file.Ledgers))
+	}
+	if ledger := findLedger(file.Ledgers, "aa"); ledger == nil || ledger.CancelledRounds != 2 {
+		t.Errorf("expected updated ledger, got %+v", ledger)
+	}
+	if ledger := findLedger(file.Ledgers, "bb"); !reflect.DeepEqual(ledger, second) {
+		t.Errorf("expected ledger of other demo to be kept, got %+v", ledger)
+	}
+}
@@ -23,7 +23,11 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 		analyser.log.WithFields(logging.Fields{
 			"tick": tick,
 		}).Error("Round end or score update called outside the match.")
-		analyser.isCancelled = true
+		analyser.cancelRound(ReasonOutsideMatch)
+		// a score update can be called in the middle of a round
+		if _, ok := e.(events.RoundEnd); ok {
+			analyser.excludeRound(tick)
+		}
 		return
 	}
 
@@ -101,7 +105,8 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 				analyser.log.WithFields(logging.Fields{
 					"tick": tick,
 				}).Error("Round end because of invalid round end reason")
-				analyser.isCancelled = true
+				analyser.cancelRound(ReasonInvalidEndReason)
+				analyser.excludeRound(tick)
 				analyser.inRound = false
 				return
 			}
@@ -109,7 +114,10 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 				analyser.log.WithFields(logging.Fields{
 					"tick": tick,
 				}).Error("No one hurted or bomb not planted in this round. Will cancelled.")
-				analyser.isCancelled = true
+				analyser.cancelRound(ReasonNoDamage)
+			}
+			if !analyser.isCancelled {
+				analyser.checkExcludedRound(tick)
 			}
 
 		} else { // second parse
@@ -133,7 +141,10 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 				"tick":  tick,
 				"event": eventString,
 			}).Error("Round end with nill states")
-			analyser.isCancelled = true
+			analyser.cancelRound(ReasonNoWinner)
+			if analyser.isFirstParse {
+				analyser.excludeRound(tick)
+			}
 			analyser.inRound = false
 			return
 		}
@@ -151,7 +162,7 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 		default:
 			// Probably match medic or something similar
 			analyser.log.Info("No winner in this round ")
-			analyser.isCancelled = true
+			analyser.cancelRound(ReasonNoWinner)
 		}
 	default:
 	}
@@ -205,6 +216,9 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 					analyser.handleClutchSituation(analyser.winnerTeam, tick)
 				}
 				analyser.handleKAST(tick)
+				analyser.recordRoundPlayers()
+				analyser.recordTeamRound()
+				analyser.commitRoundTimeline(tick)
 				analyser.checkMatchContinuity(tick)
 			}
 
@@ -227,7 +241,7 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 					"winner":   common.GetSideString(winnerTS.Team()),
 					"event":    eventString,
 				}).Error("Invalid score update.Will cancelled.")
-				analyser.isCancelled = true
+				analyser.cancelRound(ReasonInvalidScore)
 			}
 
 			// reset money set flag for each half end because
@@ -257,17 +271,26 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 					TScore: analyser.tScore, CTScore: analyser.ctScore}
 
 				analyser.validRounds[analyser.roundPlayed] = &newValidRound
+				analyser.gameRounds[analyser.roundPlayed] = analyser.gameRoundPlayed
 				analyser.log.WithFields(logging.Fields{
 					"start tick":   analyser.validRounds[analyser.roundPlayed].StartTick,
 					"end tick":     analyser.validRounds[analyser.roundPlayed].EndTick,
 					"round number": analyser.roundPlayed,
 				}).Info("New round has been added to list")
 
+				if analyser.isSinglePass {
+					analyser.endStagedRound(winnerTS.Team(), loserTS.Team(), winnerTS.ClanName, tick)
+				}
+
 			} else {
 				analyser.log.WithFields(logging.Fields{
 					"tick":  tick,
 					"event": eventString,
 				}).Error("An invalid round end.")
+				analyser.excludeRound(tick)
+				if analyser.isSinglePass {
+					analyser.discardStagedRound(tick)
+				}
 			}
 
 		} else {
@@ -277,10 +300,19 @@ func (analyser *Analyser) handleRoundEnd(e interface{}) {
 				"tick":     tick,
 				"event":    eventString,
 			}).Error("Round has already end or not start already")
+			// round has been cancelled at its start
+			if _, ok := e.(events.RoundEnd); ok && analyser.isCancelled {
+				analyser.excludeRound(tick)
+			}
 		}
 
 		// check match is over
 		analyser.checkMatchContinuity(tick)
+
+		// there is no official end for the last round of the match
+		if analyser.isSinglePass && analyser.matchEnded {
+			analyser.settleStagedRound()
+		}
 	}
 
 }
@@ -310,7 +342,7 @@ func (analyser *Analyser) handleMatchStart(eventName string) {
 			"ct number":  len(teamCT),
 			"t number":   len(teamT),
 		}).Error("Participant number is not expected for a match start.Aborted.")
-		analyser.isCancelled = true
+		analyser.cancelRound(ReasonParticipants)
 		// maybe several player can join later
 		// so set the flag for waiting
 		analyser.isPlayerWaiting = true
@@ -320,6 +352,9 @@ func (analyser *Analyser) handleMatchStart(eventName string) {
 
 	// first parse of match
 	if analyser.isFirstParse {
+		if analyser.isSinglePass {
+			analyser.settleStagedRound()
+		}
 
 		analyser.roundStart = tick
 
@@ -339,7 +374,7 @@ func (analyser *Analyser) handleMatchStart(eventName string) {
 					"played round": analyser.roundPlayed,
 					"event name":   eventName,
 				}).Error("Money has been invalid for starting round on match start")
-				analyser.isCancelled = true
+				analyser.cancelRound(ReasonInvalidMoney)
 				return
 			}
 
@@ -579,9 +614,20 @@ func (analyser *Analyser) handleRoundStart(e events.RoundStart) {
 	// }
 
 	if analyser.isFirstParse {
+		// previous round has to be finished before a new one is staged
+		if analyser.isSinglePass {
+			analyser.settleStagedRound()
+		}
+
+		// rounds after a restored backup are replayed
+		analyser.checkBackupRestore(tick)
+
 		// check match is over
 		analyser.checkMatchContinuity(tick)
 
+		// start of a round cancelled here is kept for the round ledger
+		analyser.roundStart = tick
+
 		// check money validity
 		if !analyser.checkMoneyValidity() {
 			analyser.log.WithFields(logging.Fields{
@@ -589,7 +635,7 @@ func (analyser *Analyser) handleRoundStart(e events.RoundStart) {
 				"played round": analyser.roundPlayed,
 				// "player money": analyser.getAllPlayers()[0].Money,
 			}).Error("Money has been invalid for round start")
-			analyser.isCancelled = true
+			analyser.cancelRound(ReasonInvalidMoney)
 			return
 		}
 
@@ -598,10 +644,9 @@ func (analyser *Analyser) handleRoundStart(e events.RoundStart) {
 			analyser.log.WithFields(logging.Fields{
 				"tick": tick,
 			}).Error("Round start called outside the match.")
-			analyser.isCancelled = true
+			analyser.cancelRound(ReasonOutsideMatch)
 			return
 		}
-		analyser.roundStart = tick
 
 		if analyser.inRound {
 			analyser.log.WithFields(logging.Fields{
@@ -637,6 +682,29 @@ func (analyser *Analyser) handleRoundStart(e events.RoundStart) {
 
 }
 
+// handleFreezetimeEnd count the round as participated for players alive at freeze time end.
+// Players are kept in disconnected players on disconnection, so the count survives a reconnect.
+func (analyser *Analyser) handleFreezetimeEnd(e events.RoundFreezetimeEnd) {
+	tick, err := analyser.getGameTick()
+	if err || !analyser.checkScheduledEventValid(tick) {
+		return
+	}
+
+	analyser.isParticipationCounted = true
+	for _, alivePlayers := range []map[int64]*common.PPlayer{analyser.tAlive, analyser.ctAlive} {
+		for _, pplayer := range alivePlayers {
+			pplayer.NotifyRoundParticipated()
+		}
+	}
+
+	analyser.log.WithFields(logging.Fields{
+		"tick":         tick,
+		"round number": analyser.roundPlayed,
+		"num t":        len(analyser.tAlive),
+		"num ct":       len(analyser.ctAlive),
+	}).Debug("Freeze time has been ended")
+}
+
 // handleRoundOfficiallyEnd handle round officially end event
 func (analyser *Analyser) handleRoundOfficiallyEnd(e events.RoundEndOfficial) {
 	// negative tick check
@@ -668,6 +736,9 @@ func (analyser *Analyser) handleRoundOfficiallyEnd(e events.RoundEndOfficial) {
 		if analyser.winnerTeam == p_common.TeamTerrorists || analyser.winnerTeam == p_common.TeamCounterTerrorists {
 			analyser.handleClutchSituation(analyser.winnerTeam, tick)
 		}
+		analyser.recordRoundPlayers()
+		analyser.recordTeamRound()
+		analyser.commitRoundTimeline(tick)
 		analyser.checkMatchContinuity(tick)
 
 		// reset roundoffend for duplicate calls
@@ -679,10 +750,11 @@ func (analyser *Analyser) handleRoundOfficiallyEnd(e events.RoundEndOfficial) {
 	} else {
 		// check match has already started and not yet finished
 		if !analyser.checkMatchValidity() {
-			analyser.isCancelled = true
+			analyser.cancelRound(ReasonOutsideMatch)
 			analyser.log.WithFields(logging.Fields{
 				"tick": tick,
 			}).Error("Official round end called outside the match.")
+			analyser.excludeRound(tick)
 			return
 		}
 
@@ -713,12 +785,16 @@ func (analyser *Analyser) handleRoundOfficiallyEnd(e events.RoundEndOfficial) {
 			}
 
 			analyser.curValidRound.OfficialEndTick = tick
+			if analyser.isSinglePass {
+				analyser.commitStagedRound(tick)
+			}
 
 		} else {
 			analyser.log.WithFields(logging.Fields{
 				"tick": tick,
 			}).Error("Round officially ended without proper round end")
-			analyser.isCancelled = true
+			analyser.cancelRound(ReasonNoRoundEnd)
+			analyser.excludeRound(tick)
 		}
 	}
 
@@ -0,0 +1,105 @@
+package analyser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+)
+
+// MatchMetadataSuffix suffix of the match metadata written next to a result file
+const MatchMetadataSuffix = "_match.json"
+
+// MatchMetadata metadata of a demo collected from the demo header,
+// the captured cvars and the game state
+type MatchMetadata struct {
+	// content hash of the demo
+	ID         string  `json:"id"`
+	ServerName string  `json:"server_name"`
+	ClientName string  `json:"client_name"`
+	MapName    string  `json:"map_name"`
+	MapAlias   string  `json:"map_alias"`
+	TickRate   float64 `json:"tick_rate"`
+	// playback time of the demo in seconds
+	PlaybackTime float64 `json:"playback_time"`
+	// clan names of the teams on each side at the end of the match
+	TTeam  string `json:"t_team"`
+	CTTeam string `json:"ct_team"`
+	// final scores of the sides
+	TScore  int `json:"t_score"`
+	CTScore int `json:"ct_score"`
+	// first half scores of the teams ending the match on each side
+	THalfTimeScore  int `json:"t_half_time_score"`
+	CTHalfTimeScore int `json:"ct_half_time_score"`
+	// number of overtimes played
+	NumOvertime int `json:"num_overtime"`
+	// rules of the match format
+	Rules MatchRules `json:"rules"`
+	// pauses and timeouts of the match
+	Pauses []Pause `json:"pauses"`
+	// cvars set by the server
+	Cvars              map[string]string `json:"cvars"`
+	NumValidRounds     int               `json:"num_valid_rounds"`
+	NumCancelledRounds int               `json:"num_cancelled_rounds"`
+}
+
+// buildMatchMetadata create metadata of the match
+func (analyser *Analyser) buildMatchMetadata(match MatchInfo) MatchMetadata {
+	metadata := MatchMetadata{
+		ID:                 match.ID,
+		ServerName:         analyser.header.ServerName,
+		ClientName:         analyser.header.ClientName,
+		MapName:            match.MapName,
+		MapAlias:           match.MapAlias,
+		TickRate:           analyser.tickRate,
+		PlaybackTime:       analyser.header.PlaybackTime.Seconds(),
+		TScore:             analyser.tScore,
+		CTScore:            analyser.ctScore,
+		Cvars:              analyser.cvars,
+		NumValidRounds:     len(analyser.validRounds),
+		NumCancelledRounds: analyser.cancelledRounds,
+		Rules:              analyser.rules,
+		Pauses:             analyser.pauses,
+	}
+
+	gs := analyser.parser.GameState()
+	if teamState := gs.Team(p_common.TeamTerrorists); teamState != nil {
+		metadata.TTeam = teamState.ClanName
+	}
+	if teamState := gs.Team(p_common.TeamCounterTerrorists); teamState != nil {
+		metadata.CTTeam = teamState.ClanName
+	}
+
+	// rounds won in the first half by each team
+	if rounds, ok := analyser.teamRounds[analyser.startingSide(p_common.TeamTerrorists, analyser.roundPlayed)]; ok {
+		metadata.THalfTimeScore = rounds.firstHalfRoundsWon
+	}
+	if rounds, ok := analyser.teamRounds[analyser.startingSide(p_common.TeamCounterTerrorists, analyser.roundPlayed)]; ok {
+		metadata.CTHalfTimeScore = rounds.firstHalfRoundsWon
+	}
+
+	metadata.NumOvertime = analyser.rules.numOvertime(analyser.roundPlayed)
+
+	return metadata
+}
+
+// MatchMetadataPath get path of the match metadata of a result file
+func MatchMetadataPath(outPath string) string {
+	return strings.TrimSuffix(outPath, filepath.Ext(outPath)) + MatchMetadataSuffix
+}
+
+// WriteMatchMetadata write metadata of a match result to given path as json
+func WriteMatchMetadata(path string, result *MatchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(result.Metadata)
+}
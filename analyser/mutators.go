@@ -2,6 +2,8 @@ package analyser
 
 import (
 	"bufio"
+	"io"
+	"math"
 	"time"
 
 	"github.com/golang/geo/r3"
@@ -22,12 +24,26 @@ const (
 )
 
 // ######## Initilizers and reset functions##########
-// resetAnalyser reset state of analyser
-func (analyser *Analyser) resetAnalyser() {
-	newStream := bufio.NewReader(analyser.buf)
+// resetAnalyser reset state of analyser and rewind demo stream for the second parsing
+func (analyser *Analyser) resetAnalyser() error {
+	if err := analyser.resetParser(); err != nil {
+		return err
+	}
+	analyser.resetAnalyserVars()
+
+	return nil
+}
+
+// resetParser rewind demo stream and create a new parser reading it
+func (analyser *Analyser) resetParser() error {
+	if _, err := analyser.replayStream.Seek(analyser.replayOffset, io.SeekStart); err != nil {
+		return err
+	}
+	newStream := bufio.NewReader(analyser.replayStream)
 	parser := dem.NewParserWithConfig(newStream, analyser.cfg)
 	analyser.parser = parser
-	analyser.resetAnalyserVars()
+
+	return nil
 }
 
 // resetAnalyserVars reset analyser vars
@@ -36,13 +52,21 @@ func (analyser *Analyser) resetAnalyserVars() {
 	analyser.players = make(map[int64]*common.PPlayer)
 	analyser.disconnectedPlayers = make(map[int64]*common.DisconnectedTuple)
 	analyser.roundWinners = make(map[int]string)
+	analyser.gameRounds = make(map[int]int)
+	analyser.playerNames = make(map[int64]string)
 	analyser.killPositions = nil
-	analyser.NumOvertime = 6
+	analyser.roundPlayers = nil
+	analyser.teamRounds = make(map[p_common.Team]*teamRounds)
+	analyser.timeline = nil
+	analyser.roundTimeline = nil
 	analyser.minPlayedRound = 5
 	analyser.roundPlayed = 0
 	analyser.inRound = false
 	analyser.isSuccesfulAnalyzed = false
+	analyser.result = nil
+	analyser.err = nil
 	analyser.lastCheckedTick = 0
+	analyser.isParticipationCounted = false
 }
 
 // initilizeRoundMaps initilize map vars related a round with empty maps
@@ -61,6 +85,10 @@ func (analyser *Analyser) resetRoundVars(teamT, teamCT []*p_common.Player, tick
 		"tick":         tick,
 		"round played": analyser.roundPlayed,
 	}).Info("Resetting round vars")
+	// on single pass, statistics of the round are staged until its validity is known
+	if analyser.isSinglePass {
+		analyser.stageRound(tick)
+	}
 	analyser.initilizeRoundMaps(teamT, teamCT, tick)
 	analyser.isBombPlanted = false
 	analyser.isBombDefusing = false
@@ -82,15 +110,23 @@ func (analyser *Analyser) resetRoundVars(teamT, teamCT []*p_common.Player, tick
 	analyser.isEventHappened = false
 	analyser.isPlayerWaiting = false
 	analyser.isWeaponFired = false
+	analyser.isOtherWeaponUsed = false
+	analyser.isEquipmentSet = false
+	analyser.cancelReason = ""
 	analyser.winnerTeam = p_common.TeamUnassigned
 
 	// for second parse, register map occupancy event for each round
-	if !analyser.isFirstParse {
+	if analyser.isStatPass() {
 		if analyser.navigator != nil {
 			analyser.navigator.ResetNavigator()
 
 			// calculate end of the periodic event
+			// on single pass, round end is not known yet so occupancy
+			// is checked until the staged round is committed
 			eventEndTick := analyser.roundEnd - analyser.remaningTickCheck
+			if analyser.isSinglePass {
+				eventEndTick = math.MaxInt32
+			}
 			mapControlEvent := mapControl{eventCommon: eventCommon{analyser: analyser,
 				offsetSec: analyser.periodOcccupancyCheck, isPeriodic: true, endTick: eventEndTick}}
 			analyser.customScheduler.addEvent(tick, analyser.periodOcccupancyCheck, mapControlEvent)
@@ -105,6 +141,12 @@ func (analyser *Analyser) resetMatchVars(tick int) {
 	if !analyser.checkIsMatchValid() {
 		analyser.resetScore(tick)
 		analyser.resetPlayerStates()
+		analyser.roundWinners = make(map[int]string)
+		analyser.gameRounds = make(map[int]int)
+		analyser.killPositions = nil
+		analyser.roundPlayers = nil
+		analyser.teamRounds = make(map[p_common.Team]*teamRounds)
+		analyser.timeline = nil
 		analyser.resetMatchFlags(tick)
 	} else {
 		analyser.log.WithFields(logging.Fields{
@@ -155,6 +197,44 @@ func (analyser *Analyser) notifyRoundEnd(numRoundPlayed int, winnerTeam p_common
 	}
 }
 
+// recordRoundPlayers record raw statistics of connected players for the ended round
+func (analyser *Analyser) recordRoundPlayers() {
+	for _, pplayer := range analyser.players {
+		stats := pplayer.EndRoundStats()
+		var roundType common.RoundType
+		var isAlive bool
+		switch pplayer.Team {
+		case p_common.TeamTerrorists:
+			roundType = analyser.currentTRoundType
+			_, isAlive = analyser.tAlive[pplayer.SteamID]
+		case p_common.TeamCounterTerrorists:
+			roundType = analyser.currentCTRoundType
+			_, isAlive = analyser.ctAlive[pplayer.SteamID]
+		default:
+			continue
+		}
+
+		analyser.roundPlayers = append(analyser.roundPlayers, RoundPlayerResult{
+			Round:        analyser.roundPlayed,
+			SteamID:      pplayer.SteamID,
+			Name:         analyser.playerName(pplayer),
+			Side:         common.GetSideString(pplayer.Team),
+			RoundType:    roundType.String(),
+			Kills:        stats.Kills,
+			Deaths:       stats.Deaths,
+			Assists:      stats.Assists,
+			Damage:       stats.Damage,
+			FirstKill:    stats.FirstKill,
+			FlashAssists: stats.FlashAssists,
+			MoneySaved:   stats.MoneySaved,
+			KAST:         analyser.kastPlayers[pplayer.SteamID],
+			Survived:     isAlive,
+			Winner:       common.GetSideString(analyser.winnerTeam),
+			WinnerTeam:   analyser.roundWinners[analyser.roundPlayed],
+		})
+	}
+}
+
 // notifyAllMatchEnd notify all players match has ended
 func (analyser *Analyser) notifyAllMatchEnd(tScore, ctScore int) {
 	for _, pplayer := range analyser.players {
@@ -177,6 +257,8 @@ func (analyser *Analyser) notifySquareMeter(tArea, ctArea float32, tick int) {
 			pplayer.NotifyOccupiedArea(ctArea)
 		}
 	}
+	analyser.recordTeamArea(p_common.TeamTerrorists, tArea)
+	analyser.recordTeamArea(p_common.TeamCounterTerrorists, ctArea)
 }
 
 // notifyAliveTeamMembers notify all alive players
@@ -201,14 +283,14 @@ func (analyser *Analyser) deleteAlivePlayer(side p_common.Team, uid int64) bool
 	switch side {
 	case p_common.TeamTerrorists:
 		delete(analyser.tAlive, uid)
-		if !analyser.isFirstParse {
+		if analyser.isStatPass() {
 			// after deletion check clutch situation
 			analyser.checkClutchSituation()
 		}
 		return true
 	case p_common.TeamCounterTerrorists:
 		delete(analyser.ctAlive, uid)
-		if !analyser.isFirstParse {
+		if analyser.isStatPass() {
 			// after deletion check clutch situation
 			analyser.checkClutchSituation()
 		}
@@ -319,10 +401,9 @@ func (analyser *Analyser) updateScore(newTscore, newCTscore int, eventType strin
 
 func (analyser *Analyser) swapScore(newTscore, newCTscore int) {
 	nROundsPlayed := newCTscore + newTscore
-	mpOvertimeMaxrounds := analyser.NumOvertime
-	nOvertimeHalf := mpOvertimeMaxrounds / 2
-	nOvertimeRounds := nROundsPlayed - maxRounds
-	if nROundsPlayed == 15 || nROundsPlayed == maxRounds {
+	nOvertimeHalf := analyser.rules.overtimeHalfRounds()
+	nOvertimeRounds := nROundsPlayed - analyser.rules.MaxRounds
+	if nROundsPlayed == analyser.rules.halfRounds() || nROundsPlayed == analyser.rules.MaxRounds {
 		if nROundsPlayed > analyser.lastScoreSwapped {
 			analyser.log.Info("Score has been swapped")
 			analyser.tScore, analyser.ctScore = newCTscore, newTscore
@@ -363,6 +444,8 @@ func (analyser *Analyser) setRoundStart(tick int) bool {
 			analyser.roundOffEnd = currRound.OfficialEndTick
 			analyser.curValidRound = currRound
 			analyser.roundPlayed = roundNumber
+			// events of a round without a handled end are not in the timeline
+			analyser.roundTimeline = nil
 			// register scheduled event handler
 			if roundNumber == 1 {
 				analyser.registerScheduler()
@@ -506,7 +589,7 @@ func (analyser *Analyser) findRoundTypeByMoney(eqValue int) (common.RoundType, s
 
 	// pistol round handling only normal time
 	// first round of each halfs
-	if analyser.roundPlayed <= 30 && analyser.roundPlayed%15 == 1 {
+	if analyser.rules.isPistolRound(analyser.currentRound()) {
 		roundTypeStr = "PistolRound"
 		roundType = common.PistolRound
 	} else {
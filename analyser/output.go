@@ -1,23 +1,16 @@
 package analyser
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
 
-	common "github.com/markus-wa/demoinfocs-golang/common"
-	utils "github.com/quancore/demoanalyzer-go/utils"
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	common "github.com/quancore/demoanalyzer-go/common"
 	logging "github.com/sirupsen/logrus"
-
-	"github.com/spf13/viper"
 )
 
 const (
 	specifier = ","
-	// if special char is in a string
-	// it will replace with space
-	replaceWithSpace = true
 )
 
 // ############## Printer / writers #############
@@ -25,18 +18,20 @@ const (
 func (analyser *Analyser) printPlayers() {
 	analyser.log.Info("#########################################")
 
-	teamWon := analyser.getWinnerTeam()
-	gs := analyser.parser.GameState()
-	teamWonState := gs.Team(teamWon)
+	// there is no winner team of a draw
+	var winnerName string
+	if teamWon := analyser.getWinnerTeam(); teamWon != p_common.TeamUnassigned {
+		winnerName = analyser.parser.GameState().Team(teamWon).ClanName
+	}
 
 	tScore, ctScore := analyser.tScore, analyser.ctScore
 
 	analyser.log.WithFields(logging.Fields{
 		"t score":             tScore,
 		"ct score":            ctScore,
-		"winner team":         teamWonState.ClanName,
+		"winner team":         winnerName,
 		"played round":        analyser.roundPlayed,
-		"round winner string": analyser.createRoundString(teamWonState.ClanName),
+		"round winner string": analyser.createRoundString(winnerName),
 	}).Info("Match has been finished: ")
 
 	for _, currPlayer := range analyser.getAllPlayers() {
@@ -137,93 +132,25 @@ func (analyser *Analyser) createRoundString(winnerTeamName string) string {
 	return sb.String()
 }
 
-// printPlayers print player stats after finished the match
-func (analyser *Analyser) writeToFile(path string) {
-	file, err := os.Create(path)
-	utils.CheckError(err)
-	w := bufio.NewWriter(file)
-	var sb strings.Builder
-	defer file.Close()
-	features := viper.GetString("output.features")
-	analyzerVersion := viper.GetString("output.analyzer_version")
-	mapnameAlias := viper.GetStringMapString("mapnameAlias")
-	roundPlayed := analyser.roundPlayed
-
-	// if test needed for output
-	istestrequired := viper.GetBool("checkanalyzer")
-	mapname := analyser.mapName
-	analyser.log.WithFields(logging.Fields{
-		"name": mapname,
-	}).Info("Mapname: ")
-
-	if newMapname, ok := mapnameAlias[mapname]; ok {
-		analyser.log.WithFields(logging.Fields{
-			"new name": newMapname,
-			"old name": mapname,
-		}).Info("Mapname changed: ")
-		mapname = newMapname
-	}
-	if istestrequired {
-		analyser.testGameState()
-		analyser.testParticipant()
-	}
-
-	gs := analyser.parser.GameState()
-
-	teamWon := analyser.getWinnerTeam()
-	roundString := analyser.createRoundString(gs.Team(teamWon).ClanName)
-
-	w.WriteString(fmt.Sprintf("version=%s, demo_mapname=%s, round_played=%d, round_winners=%s",
-		analyzerVersion, mapname, roundPlayed, roundString))
-	w.WriteByte('\n')
-	w.Flush()
-	w.WriteString(features)
-	w.WriteByte('\n')
-	w.Flush()
-
-	analyser.log.WithFields(logging.Fields{
-		"t score":      analyser.tScore,
-		"ct score":     analyser.ctScore,
-		"played round": analyser.roundPlayed,
-		"winner team":  gs.Team(teamWon).ClanName,
-		"writing path": path,
-	}).Info("Writing to file: ")
+// WriteMatchResult write player features of a match result to given path in text format
+func WriteMatchResult(path string, result *MatchResult) error {
+	return WriteResult(FormatText, path, result)
+}
 
-	for _, currPlayer := range analyser.getAllPlayers() {
-		// if currPlayer.TeamState == nil {
-		// 	analyser.log.WithFields(logging.Fields{
-		// 		"name": currPlayer.Name,
-		// 		"team": currPlayer.Team,
-		// 	}).Info("Team state is null for player ")
-		// 	continue
-		if !(analyser.checkTeamValidity(currPlayer.Team)) {
-			analyser.log.WithFields(logging.Fields{
-				"name":     currPlayer.Name,
-				"team":     currPlayer.Team,
-				"old team": currPlayer.GetOldTeam(),
-			}).Info("Player team or old team is wrong ")
-			continue
-		} else if currPlayer.GetNumKills() <= 0 && currPlayer.GetNumDeaths() <= 0 {
-			analyser.log.WithFields(logging.Fields{
-				"name":      currPlayer.Name,
-				"team":      currPlayer.Team,
-				"num kill":  currPlayer.GetNumKills(),
-				"num death": currPlayer.GetNumDeaths(),
-			}).Info("Player has wrong stat ")
-			continue
-		}
+// textMatchResult create text output of a match result
+func textMatchResult(result *MatchResult) string {
+	var sb strings.Builder
 
-		winLabel := 0
-		// if there is equality or player team won set to 1
-		if teamWon == common.TeamUnassigned || currPlayer.Team == teamWon {
-			winLabel = 1
-		}
-		sb = currPlayer.OutputPlayerState(sb, analyser.roundPlayed, winLabel, analyser.tScore, analyser.ctScore)
+	match := result.Match
+	sb.WriteString(fmt.Sprintf("version=%s, demo_mapname=%s, round_played=%d, round_winners=%s",
+		match.AnalyzerVersion, match.MapAlias, match.RoundPlayed, match.RoundWinners))
+	sb.WriteByte('\n')
+	sb.WriteString(fmt.Sprintf("SteamID%sName%s%s%sWon", specifier, specifier, strings.Join(result.FeatureNames, specifier), specifier))
+	sb.WriteByte('\n')
 
+	for _, player := range result.Players {
+		sb = common.OutputPlayerState(sb, player.SteamID, player.Name, player.Features, player.Won)
 	}
 
-	w.WriteString(sb.String())
-	w.Flush()
-	sb.Reset()
-
+	return sb.String()
 }
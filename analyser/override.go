@@ -0,0 +1,131 @@
+package analyser
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+)
+
+// OverrideExt extension of round override file
+const OverrideExt = ".override.json"
+
+// reasons of overriding the first parsing
+const (
+	// round has been excluded by the override file
+	ReasonOverrideExclude = "override_exclude"
+	// round has been played before the match start tick of the override file
+	ReasonBeforeMatchStart = "before_match_start"
+	// round has been included by the override file
+	ReasonOverrideInclude = "override_include"
+)
+
+// RoundOverride manual corrections of the rounds found in the first parsing.
+// Rounds are identified by their start ticks as listed in the round ledger.
+type RoundOverride struct {
+	// start ticks of rounds to include in the match
+	IncludeRounds []int `json:"include_rounds"`
+	// start ticks of rounds to exclude from the match
+	ExcludeRounds []int `json:"exclude_rounds"`
+	// rounds starting before this tick are excluded, zero to keep detected match start
+	MatchStartTick int `json:"match_start_tick"`
+}
+
+// OverridePath get path of the round override file of a demo. For a demo
+// inside an archive, name of the demo in the archive is added to the path.
+func OverridePath(demoPath, entryName string) string {
+	if entryName == "" {
+		return demoPath + OverrideExt
+	}
+
+	return demoPath + "." + strings.Replace(entryName, "/", "_", -1) + OverrideExt
+}
+
+// SetOverridePath set path of the round override file. It is ignored if
+// the file does not exist or the demo is analysed in a single pass.
+func (analyser *Analyser) SetOverridePath(path string) { analyser.overridePath = path }
+
+// loadOverride read round override file, nil is returned if there is no file
+func (analyser *Analyser) loadOverride() (*RoundOverride, error) {
+	content, err := ioutil.ReadFile(analyser.overridePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	override := &RoundOverride{}
+	if err := json.Unmarshal(content, override); err != nil {
+		return nil, err
+	}
+
+	return override, nil
+}
+
+// applyOverride include and exclude rounds found in the first parsing and
+// renumber valid rounds in the order of their start ticks
+func (analyser *Analyser) applyOverride(override *RoundOverride) {
+	excludeTicks := make(map[int]bool)
+	for _, startTick := range override.ExcludeRounds {
+		excludeTicks[startTick] = true
+	}
+	includeTicks := make(map[int]bool)
+	for _, startTick := range override.IncludeRounds {
+		includeTicks[startTick] = true
+	}
+
+	var rounds []*common.RoundTuples
+	for roundNumber, round := range analyser.validRounds {
+		reason := ""
+		if excludeTicks[round.StartTick] {
+			reason = ReasonOverrideExclude
+		} else if round.StartTick < override.MatchStartTick {
+			reason = ReasonBeforeMatchStart
+		}
+		if reason == "" {
+			rounds = append(rounds, round)
+			continue
+		}
+		analyser.excludedRounds = append(analyser.excludedRounds, ExcludedRound{
+			Round:           roundNumber,
+			StartTick:       round.StartTick,
+			EndTick:         round.EndTick,
+			OfficialEndTick: round.OfficialEndTick,
+			TScore:          round.TScore,
+			CTScore:         round.CTScore,
+			Reason:          reason,
+		})
+		analyser.log.WithFields(logging.Fields{
+			"round":      roundNumber,
+			"start tick": round.StartTick,
+			"reason":     reason,
+		}).Info("Round has been excluded by override")
+	}
+
+	var excludedRounds []ExcludedRound
+	for _, excluded := range analyser.excludedRounds {
+		// warmup phases and rounds excluded by the override can not be included
+		if !includeTicks[excluded.StartTick] || excludeTicks[excluded.StartTick] || excluded.Reason == ReasonWarmup {
+			excludedRounds = append(excludedRounds, excluded)
+			continue
+		}
+		rounds = append(rounds, &common.RoundTuples{StartTick: excluded.StartTick, EndTick: excluded.EndTick,
+			OfficialEndTick: excluded.OfficialEndTick, TScore: excluded.TScore, CTScore: excluded.CTScore})
+		analyser.includedRounds[excluded.StartTick] = true
+		analyser.log.WithFields(logging.Fields{
+			"start tick": excluded.StartTick,
+			"reason":     excluded.Reason,
+		}).Info("Round has been included by override")
+	}
+	analyser.excludedRounds = excludedRounds
+
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i].StartTick < rounds[j].StartTick })
+	analyser.validRounds = make(map[int]*common.RoundTuples)
+	for i, round := range rounds {
+		analyser.validRounds[i+1] = round
+	}
+}
@@ -0,0 +1,65 @@
+package analyser
+
+import (
+	"io/ioutil"
+	"testing"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+)
+
+// TestApplyOverride test that overridden rounds are renumbered in the order of their start ticks
+func TestApplyOverride(t *testing.T) {
+	log := logging.New()
+	log.Out = ioutil.Discard
+	analyser := &Analyser{
+		log: log,
+		validRounds: map[int]*common.RoundTuples{
+			1: {StartTick: 100, EndTick: 140, OfficialEndTick: 145, TScore: 1},
+			2: {StartTick: 200, EndTick: 240, OfficialEndTick: 245, TScore: 1, CTScore: 1},
+			3: {StartTick: 300, EndTick: 340, OfficialEndTick: 345, TScore: 2, CTScore: 1},
+		},
+		excludedRounds: []ExcludedRound{
+			{StartTick: 10, EndTick: 90, Reason: ReasonWarmup},
+			{Round: 2, StartTick: 150, EndTick: 180, OfficialEndTick: 190, CTScore: 1, Reason: ReasonNoDamage},
+			{Round: 3, StartTick: 250, EndTick: 280, Reason: ReasonInvalidMoney},
+		},
+		includedRounds: make(map[int]bool),
+	}
+
+	analyser.applyOverride(&RoundOverride{
+		IncludeRounds:  []int{150, 10},
+		ExcludeRounds:  []int{300},
+		MatchStartTick: 120,
+	})
+
+	expected := []common.RoundTuples{
+		{StartTick: 150, EndTick: 180, OfficialEndTick: 190, CTScore: 1},
+		{StartTick: 200, EndTick: 240, OfficialEndTick: 245, TScore: 1, CTScore: 1},
+	}
+	if len(analyser.validRounds) != len(expected) {
+		t.Fatalf("expected %d valid rounds, got %d", len(expected), len(analyser.validRounds))
+	}
+	for i, round := range expected {
+		if actual, ok := analyser.validRounds[i+1]; !ok || *actual != round {
+			t.Errorf("round %d: expected %+v, got %+v", i+1, round, actual)
+		}
+	}
+	if !analyser.includedRounds[150] || analyser.includedRounds[10] {
+		t.Errorf("unexpected included rounds %v", analyser.includedRounds)
+	}
+
+	reasons := make(map[int]string)
+	for _, excluded := range analyser.excludedRounds {
+		reasons[excluded.StartTick] = excluded.Reason
+	}
+	expectedReasons := map[int]string{10: ReasonWarmup, 100: ReasonBeforeMatchStart, 250: ReasonInvalidMoney, 300: ReasonOverrideExclude}
+	if len(reasons) != len(expectedReasons) || len(analyser.excludedRounds) != len(expectedReasons) {
+		t.Fatalf("expected excluded rounds %v, got %v", expectedReasons, analyser.excludedRounds)
+	}
+	for startTick, reason := range expectedReasons {
+		if reasons[startTick] != reason {
+			t.Errorf("round starting at %d: expected reason %s, got %s", startTick, reason, reasons[startTick])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package analyser
+
+import (
+	"fmt"
+
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	st "github.com/markus-wa/demoinfocs-golang/sendtables"
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+)
+
+// kinds of a pause
+const (
+	// match has been paused by an admin (mp_pause_match)
+	PauseKindMatch = "match_pause"
+	// tactical timeout called by a team
+	PauseKindTactical = "tactical_timeout"
+	// technical timeout
+	PauseKindTechnical = "technical_timeout"
+)
+
+// ReasonBackupRestore rounds replayed after a backup restore
+const ReasonBackupRestore = "backup_restore"
+
+// game rules properties of pauses and their kinds and calling sides
+var pauseProps = []struct {
+	name string
+	kind string
+	side p_common.Team
+}{
+	{"cs_gamerules_data.m_bMatchWaitingForResume", PauseKindMatch, p_common.TeamUnassigned},
+	{"cs_gamerules_data.m_bTerroristTimeOutActive", PauseKindTactical, p_common.TeamTerrorists},
+	{"cs_gamerules_data.m_bCTTimeOutActive", PauseKindTactical, p_common.TeamCounterTerrorists},
+	{"cs_gamerules_data.m_bTechnicalTimeOut", PauseKindTechnical, p_common.TeamUnassigned},
+}
+
+// Pause a pause of the match
+type Pause struct {
+	Kind string `json:"kind"`
+	// number of rounds played before the pause
+	Round     int `json:"round"`
+	StartTick int `json:"start_tick"`
+	EndTick   int `json:"end_tick"`
+	// duration of the pause in seconds
+	Duration float64 `json:"duration"`
+	// clan name and side of the team called the pause, empty if it is not called by a team
+	Team string `json:"team"`
+	Side string `json:"side"`
+}
+
+// registerGameRulesHandlers register handlers of game rules properties for pauses
+func (analyser *Analyser) registerGameRulesHandlers() {
+	gameRules := analyser.parser.ServerClasses().FindByName("CCSGameRulesProxy")
+	if gameRules == nil {
+		analyser.log.Error("Game rules server class has not been found, pauses will not be recorded")
+		return
+	}
+	gameRules.OnEntityCreated(func(entity *st.Entity) {
+		for _, pauseProp := range pauseProps {
+			prop := entity.FindProperty(pauseProp.name)
+			if prop == nil {
+				continue
+			}
+			kind, side := pauseProp.kind, pauseProp.side
+			prop.OnUpdate(func(val st.PropertyValue) { analyser.handlePauseChange(kind, side, val.IntVal != 0) })
+		}
+	})
+}
+
+// handlePauseChange record start and end of a pause
+func (analyser *Analyser) handlePauseChange(kind string, side p_common.Team, isActive bool) {
+	tick, err := analyser.getGameTick()
+	if err || !analyser.isFirstParse {
+		return
+	}
+
+	pause, ok := analyser.activePauses[kind+common.GetSideString(side)]
+	if isActive {
+		if ok {
+			return
+		}
+		pause = &Pause{Kind: kind, Round: analyser.roundPlayed, StartTick: tick}
+		if side != p_common.TeamUnassigned {
+			pause.Side = common.GetSideString(side)
+			if teamState := analyser.parser.GameState().Team(side); teamState != nil {
+				pause.Team = teamState.ClanName
+			}
+		}
+		analyser.activePauses[kind+common.GetSideString(side)] = pause
+		analyser.log.WithFields(logging.Fields{
+			"tick": tick,
+			"kind": kind,
+			"team": pause.Team,
+		}).Info("Match has been paused")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	delete(analyser.activePauses, kind+common.GetSideString(side))
+	pause.EndTick = tick
+	pause.Duration = common.TickToSeconds(tick-pause.StartTick, analyser.tickRate).Seconds()
+	analyser.pauses = append(analyser.pauses, *pause)
+	analyser.log.WithFields(logging.Fields{
+		"tick":     tick,
+		"kind":     kind,
+		"team":     pause.Team,
+		"duration": pause.Duration,
+	}).Info("Match has been resumed")
+}
+
+// checkBackupRestore check whether a backup of an earlier round has been
+// restored. Rounds played after the restored round are discarded since
+// they are replayed. Rounds played by the game include cancelled rounds,
+// so a restore is detected by the game's own count of rounds.
+func (analyser *Analyser) checkBackupRestore(tick int) {
+	gs := analyser.parser.GameState()
+	restoredRound := gs.TotalRoundsPlayed()
+	lastGameRoundPlayed := analyser.gameRoundPlayed
+	analyser.gameRoundPlayed = restoredRound
+	// a restart sets rounds played to zero and it is handled as match start
+	if !analyser.matchStarted || restoredRound <= 0 || restoredRound >= lastGameRoundPlayed {
+		return
+	}
+
+	analyser.log.WithFields(logging.Fields{
+		"tick":           tick,
+		"round played":   analyser.roundPlayed,
+		"game round":     lastGameRoundPlayed,
+		"restored round": restoredRound,
+	}).Error("Backup of an earlier round has been restored, replayed rounds are discarded")
+	// committed statistics of the replayed rounds would be counted twice
+	if analyser.isSinglePass {
+		analyser.setError(newAnalyzeError(ErrBackupRestored,
+			fmt.Errorf("round %d restored after round %d, analyze the demo in two passes", restoredRound, lastGameRoundPlayed)))
+		return
+	}
+
+	analyser.discardReplayedRounds(restoredRound)
+	if tTeam, ctTeam := gs.TeamTerrorists(), gs.TeamCounterTerrorists(); tTeam != nil && ctTeam != nil {
+		analyser.tScore, analyser.ctScore = tTeam.Score, ctTeam.Score
+	}
+}
+
+// discardReplayedRounds exclude valid rounds started after the game had played given number of rounds
+func (analyser *Analyser) discardReplayedRounds(restoredRound int) {
+	roundPlayed := analyser.roundPlayed
+	for roundNumber := analyser.roundPlayed; roundNumber > 0; roundNumber-- {
+		if gameRound, ok := analyser.gameRounds[roundNumber]; !ok || gameRound < restoredRound {
+			break
+		}
+		if round, ok := analyser.validRounds[roundNumber]; ok {
+			analyser.cancelledRounds++
+			analyser.excludedRounds = append(analyser.excludedRounds, ExcludedRound{
+				Round:           roundNumber,
+				StartTick:       round.StartTick,
+				EndTick:         round.EndTick,
+				OfficialEndTick: round.OfficialEndTick,
+				TScore:          round.TScore,
+				CTScore:         round.CTScore,
+				Reason:          ReasonBackupRestore,
+			})
+		}
+		delete(analyser.validRounds, roundNumber)
+		delete(analyser.roundWinners, roundNumber)
+		delete(analyser.gameRounds, roundNumber)
+		roundPlayed = roundNumber - 1
+	}
+
+	analyser.roundPlayed = roundPlayed
+	if analyser.lastScoreSwapped > roundPlayed {
+		analyser.lastScoreSwapped = roundPlayed
+	}
+}
@@ -0,0 +1,60 @@
+package analyser
+
+import (
+	"io/ioutil"
+	"testing"
+
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+)
+
+// TestDiscardReplayedRounds test that rounds are discarded by the game's count of rounds
+// when a backup is restored after a cancelled round
+func TestDiscardReplayedRounds(t *testing.T) {
+	log := logging.New()
+	log.Out = ioutil.Discard
+	analyser := &Analyser{
+		log:         log,
+		roundPlayed: 4,
+		validRounds: map[int]*common.RoundTuples{
+			1: {StartTick: 100, EndTick: 140, OfficialEndTick: 145, TScore: 1},
+			2: {StartTick: 300, EndTick: 340, OfficialEndTick: 345, TScore: 2},
+			3: {StartTick: 400, EndTick: 440, OfficialEndTick: 445, TScore: 2, CTScore: 1},
+			4: {StartTick: 500, EndTick: 540, OfficialEndTick: 545, TScore: 3, CTScore: 1},
+		},
+		// second round of the game has been cancelled
+		gameRounds:       map[int]int{1: 0, 2: 2, 3: 3, 4: 4},
+		roundWinners:     map[int]string{1: "a", 2: "a", 3: "b", 4: "a"},
+		cancelledRounds:  1,
+		excludedRounds:   []ExcludedRound{{Round: 2, StartTick: 200, EndTick: 240, TScore: 1, Reason: ReasonNoDamage}},
+		lastScoreSwapped: 4,
+	}
+
+	// backup of the fourth round of the game is restored
+	analyser.discardReplayedRounds(3)
+
+	if analyser.roundPlayed != 2 {
+		t.Errorf("expected 2 rounds played, got %d", analyser.roundPlayed)
+	}
+	if len(analyser.validRounds) != 2 || analyser.validRounds[1] == nil || analyser.validRounds[2] == nil {
+		t.Errorf("expected first 2 valid rounds to be kept, got %v", analyser.validRounds)
+	}
+	if len(analyser.roundWinners) != 2 || len(analyser.gameRounds) != 2 {
+		t.Errorf("expected winners and game rounds of 2 rounds, got %v and %v", analyser.roundWinners, analyser.gameRounds)
+	}
+	if analyser.cancelledRounds != 3 {
+		t.Errorf("expected 3 cancelled rounds, got %d", analyser.cancelledRounds)
+	}
+	if analyser.lastScoreSwapped != 2 {
+		t.Errorf("expected last score swap at round 2, got %d", analyser.lastScoreSwapped)
+	}
+	var restored []int
+	for _, excluded := range analyser.excludedRounds {
+		if excluded.Reason == ReasonBackupRestore {
+			restored = append(restored, excluded.StartTick)
+		}
+	}
+	if len(restored) != 2 || restored[0] != 500 || restored[1] != 400 {
+		t.Errorf("expected replayed rounds starting at 500 and 400, got %v", restored)
+	}
+}
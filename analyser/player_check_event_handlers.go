@@ -53,3 +53,47 @@ func (analyser *Analyser) handleCheckKill(e events.Kill, tick int) {
 
 	}
 }
+
+// handleCheckHurt check late match start and record a player has been hurt in the round
+func (analyser *Analyser) handleCheckHurt(e events.PlayerHurt, tick int) {
+	// get player pointers
+	_, _, victimOK, attackerOK := analyser.checkEventValidity(e.Player, e.Attacker, "playerHurt", true, tick)
+
+	if !victimOK || !attackerOK {
+		return
+	}
+
+	// check is there any player waiting for round start
+	// if we are in first parse and several players are waiting to join or got disconnected
+	// and this is the first hurt event for this round, check missed players
+	// has been join the game
+	if analyser.isFirstParse && analyser.isPlayerWaiting && !analyser.isPlayerHurt {
+		if _, _, ok := analyser.checkParticipantValidity(tick); ok {
+			analyser.log.WithFields(logging.Fields{
+				"tick": tick,
+			}).Info("Late match start has been triggered with player hurt event")
+			analyser.isCancelled = false
+			analyser.cancelReason = ""
+			analyser.isPlayerWaiting = false
+			// call match start again
+			analyser.handleMatchStart("late_match_start")
+		}
+	} else if analyser.isPlayerWaiting {
+		analyser.log.WithFields(logging.Fields{
+			"tick":           tick,
+			"is player hurt": analyser.isPlayerHurt,
+		}).Info("No late match start has been triggered")
+	}
+
+	// set a player has been hurt in this round
+	analyser.isPlayerHurt = true
+}
+
+// handleCheckBombPlanted record bomb has been planted in the round
+func (analyser *Analyser) handleCheckBombPlanted(e events.BombPlanted, tick int) {
+	analyser.log.WithFields(logging.Fields{
+		"tick":    tick,
+		"user id": e.Player.SteamID,
+	}).Info("Bomb has been planted: ")
+	analyser.isBombPlanted = true
+}
@@ -13,7 +13,7 @@ import (
 
 // dispatchPlayerEvents common function to handle a player event
 // useful for common checks for all events
-// used for second time parsing
+// used for second time parsing or single pass analyze
 func (analyser *Analyser) dispatchPlayerEvents(e interface{}) {
 	// set event happened to true for this round
 	analyser.isEventHappened = true
@@ -29,7 +29,15 @@ func (analyser *Analyser) dispatchPlayerEvents(e interface{}) {
 	// it is nearly impossible to a player event can happen at the same
 	// time with an round start or end.It is much common to be a server event
 	// like killing all players at the same time for a game reset
-	if !analyser.setRoundStart(tick) || (tick == analyser.roundStart || tick == analyser.roundOffEnd) {
+	if analyser.isSinglePass {
+		if !analyser.checkStagedEventValid(tick) {
+			analyser.log.WithFields(logging.Fields{
+				"tick":       tick,
+				"start tick": analyser.roundStart,
+			}).Debug("Event outside of staged round: ")
+			return
+		}
+	} else if !analyser.setRoundStart(tick) || (tick == analyser.roundStart || tick == analyser.roundOffEnd) {
 		analyser.log.WithFields(logging.Fields{
 			"tick":       tick,
 			"start tick": analyser.roundStart,
@@ -38,6 +46,8 @@ func (analyser *Analyser) dispatchPlayerEvents(e interface{}) {
 		return
 	}
 
+	analyser.recordTimeline(e, tick)
+
 	// dispatch event to its handler
 	switch e.(type) {
 	case events.Kill:
@@ -147,7 +157,7 @@ func (analyser *Analyser) handleKill(e events.Kill, tick int) {
 		if analyser.mapMetadata != nil {
 			x, y := analyser.mapMetadata.TranslateScale(killer.Position.X, killer.Position.Y)
 			newPoint := &common.KillPosition{Tick: tick,
-				RoundNumber: analyser.roundPlayed,
+				RoundNumber: analyser.currentRound(),
 				KillPoint:   r2.Point{X: x, Y: y},
 				VictimID:    victimID,
 				KillerID:    killerID,
@@ -289,27 +299,6 @@ func (analyser *Analyser) handleHurt(e events.PlayerHurt, tick int) {
 	// check team side validity
 	_, _, sideOK := analyser.checkTeamSideValidity(victim, attacker, "playerHurt", tick)
 
-	// check is there any player waiting for round start
-	// if we are in first parse and several players are waiting to join or got disconnected
-	// and this is the first hurt event for this round, check missed players
-	// has been join the game
-	if analyser.isFirstParse && analyser.isPlayerWaiting && !analyser.isPlayerHurt {
-		if _, _, ok := analyser.checkParticipantValidity(tick); ok {
-			analyser.log.WithFields(logging.Fields{
-				"tick": tick,
-			}).Info("Late match start has been triggered with player hurt event")
-			analyser.isCancelled = false
-			analyser.isPlayerWaiting = false
-			// call match start again
-			analyser.handleMatchStart("late_match_start")
-		}
-	} else if analyser.isPlayerWaiting {
-		analyser.log.WithFields(logging.Fields{
-			"tick":           tick,
-			"is player hurt": analyser.isPlayerHurt,
-		}).Info("No late match start has been triggered")
-	}
-
 	// handle victim
 	analyser.log.WithFields(logging.Fields{
 		"tick":     tick,
@@ -336,7 +325,7 @@ func (analyser *Analyser) handleHurt(e events.PlayerHurt, tick int) {
 	analyser.isPlayerHurt = true
 
 	// notify events for the second parse session
-	if !analyser.isFirstParse && sideOK {
+	if analyser.isStatPass() && sideOK {
 		// did hurt with grenade class
 		if weaponType == p_common.EqClassGrenade {
 			if e.Weapon.Weapon == p_common.EqHE { // he damage
@@ -363,7 +352,7 @@ func (analyser *Analyser) handleWeaponFire(e events.WeaponFire, tick int) {
 
 	// determine the type of round in the first
 	// player hurt event of second parsing
-	if !(analyser.isWeaponFired || analyser.isFirstParse) {
+	if !analyser.isWeaponFired && analyser.isStatPass() {
 		analyser.setRoundType(tick)
 	}
 
@@ -484,7 +473,7 @@ func (analyser *Analyser) handlePlayerFlashed(e events.PlayerFlashed, tick int)
 		"flash duration": duration,
 	}).Info("Player flashed: ")
 
-	if !analyser.isFirstParse {
+	if analyser.isStatPass() {
 		// calculate last valid tick the flash event will be effective
 		tickRate := analyser.parser.Header().TickRate()
 		flashLenght := tickRate * duration.Seconds()
@@ -714,13 +703,19 @@ func (analyser *Analyser) handleSpecialRound(winnerT, loserT p_common.Team, tick
 	// winner team
 	for _, currPlayer := range winnerTeamPlayers {
 		if NewPPlayer, ok := analyser.getPlayerByID(currPlayer.SteamID, false); ok {
-			NewPPlayer.NotifySpecialRoundWon(winnerRoundType)
+			if err := NewPPlayer.NotifySpecialRoundWon(winnerRoundType); err != nil {
+				analyser.setError(err)
+				return
+			}
 		}
 	}
 	// loser team
 	for _, currPlayer := range loserTeamPlayers {
 		if NewPPlayer, ok := analyser.getPlayerByID(currPlayer.SteamID, false); ok {
-			NewPPlayer.NotifySpecialRoundLost(loserRoundType)
+			if err := NewPPlayer.NotifySpecialRoundLost(loserRoundType); err != nil {
+				analyser.setError(err)
+				return
+			}
 		}
 	}
 
@@ -52,7 +52,7 @@ func (p SimplePlotter) ClusterCenter(points point.Points) *point.Point {
 }
 
 // Plot draw a 2-dimensional data set into a PNG file named {k_iteration}.png
-func (p SimplePlotter) Plot(outPath, mapname string, bounds image.Rectangle, cc []point.Points) {
+func (p SimplePlotter) Plot(outPath, mapname string, bounds image.Rectangle, cc []point.Points) error {
 	var series []chart.Series
 	var clusterCenters point.Points
 
@@ -108,7 +108,9 @@ func (p SimplePlotter) Plot(outPath, mapname string, bounds image.Rectangle, cc
 
 	buffer := bytes.NewBuffer([]byte{})
 	err := graph.Render(chart.PNG, buffer)
-	utils.CheckError(err)
+	if err != nil {
+		return err
+	}
 
 	clusterImg, _, _ := image.Decode(bytes.NewReader(buffer.Bytes()))
 
@@ -117,9 +119,14 @@ func (p SimplePlotter) Plot(outPath, mapname string, bounds image.Rectangle, cc
 	// read base radar map
 	gopath := utils.GetGoPath()
 	fMap, err := os.Open(fmt.Sprintf("%s/src/github.com/markus-wa/demoinfocs-golang/metadata/maps/%s.jpg", gopath, mapname))
-	utils.CheckError(err)
+	if err != nil {
+		return err
+	}
+	defer fMap.Close()
 	imgMap, _, err := image.Decode(fMap)
-	utils.CheckError(err)
+	if err != nil {
+		return err
+	}
 
 	// Create output canvas and use map overview image as base
 	img := image.NewRGBA(imgMap.Bounds())
@@ -129,11 +136,11 @@ func (p SimplePlotter) Plot(outPath, mapname string, bounds image.Rectangle, cc
 	draw.Draw(img, bounds, clusterImg, image.ZP, draw.Over)
 
 	// Write to stdout
-	f, OutErr := os.Create(outPath)
-	utils.CheckError(OutErr)
-
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
-	err = jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
-	utils.CheckError(err)
 
+	return jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
 }
@@ -0,0 +1,48 @@
+package analyser
+
+// passes of an analyze
+const (
+	// FirstPass parsing to find valid rounds, it is the only
+	// parsing of a single pass analyze
+	FirstPass = 1
+	// SecondPass parsing to collect player statistics
+	SecondPass = 2
+)
+
+// min change of parsed fraction to report a progress
+const progressStep = 0.01
+
+// Progress progress of an analyze
+type Progress struct {
+	// FirstPass or SecondPass
+	Pass int `json:"pass"`
+	// fraction of the demo parsed in the current pass, between 0 and 1
+	Fraction float32 `json:"fraction"`
+	// number of the round currently played
+	Round int `json:"round"`
+}
+
+// ProgressHandler function called when an analyze progressed
+type ProgressHandler func(Progress)
+
+// SetProgressHandler set the function called when the analyze progressed.
+// The handler is called from the goroutine running Analyze.
+func (analyser *Analyser) SetProgressHandler(handler ProgressHandler) {
+	analyser.progressHandler = handler
+}
+
+// reportProgress call progress handler if the analyze progressed enough since last report
+func (analyser *Analyser) reportProgress(pass int) {
+	if analyser.progressHandler == nil {
+		return
+	}
+
+	progress := Progress{Pass: pass, Fraction: analyser.parser.Progress(), Round: analyser.currentRound()}
+	last := analyser.lastProgress
+	isFinished := progress.Fraction >= 1 && last.Fraction < 1
+	if !isFinished && progress.Pass == last.Pass && progress.Round == last.Round && progress.Fraction-last.Fraction < progressStep {
+		return
+	}
+	analyser.lastProgress = progress
+	analyser.progressHandler(progress)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/markus-wa/demoinfocs-golang/events"
 	"github.com/markus-wa/demoinfocs-golang/msg"
+	common "github.com/quancore/demoanalyzer-go/common"
 	logging "github.com/sirupsen/logrus"
 )
 
@@ -15,12 +16,7 @@ func (analyser *Analyser) registerNetMessageHandlers() {
 	// Register handler for net messages updates
 	analyser.parser.RegisterNetMessageHandler(func(m *msg.CNETMsg_SetConVar) {
 		for _, cvar := range m.Convars.Cvars {
-			if cvar.Name == "mp_overtime_maxrounds" {
-				analyser.NumOvertime, _ = strconv.Atoi(cvar.Value)
-			} else if cvar.Name == "mp_startmoney" {
-				analyser.currentSMoney, _ = strconv.ParseFloat(cvar.Value, 64)
-				analyser.isMoneySet = true
-			}
+			analyser.setConVar(cvar.Name, cvar.Value)
 			analyser.log.WithFields(logging.Fields{
 				"cvar name":  cvar.Name,
 				"cvar value": cvar.Value,
@@ -29,6 +25,16 @@ func (analyser *Analyser) registerNetMessageHandlers() {
 	})
 }
 
+// setConVar record a cvar and set match related variable of it
+func (analyser *Analyser) setConVar(name, value string) {
+	analyser.cvars[name] = value
+	analyser.setRuleCvar(name, value)
+	if name == "mp_startmoney" {
+		analyser.currentSMoney, _ = strconv.ParseFloat(value, 64)
+		analyser.isMoneySet = true
+	}
+}
+
 // registerMatchEventHandlers register event handlers of match events
 func (analyser *Analyser) registerMatchEventHandlers() {
 	// *********** match events ********************
@@ -43,6 +49,10 @@ func (analyser *Analyser) registerMatchEventHandlers() {
 	analyser.parser.RegisterEventHandler(func(e events.PlayerDisconnected) { analyser.handlePlayerDisconnect(e) })
 	// Register handler on game phase changed. Useful for match end
 	// analyser.parser.RegisterEventHandler(func(e events.GamePhaseChanged) { analyser.handleGamePhaseChange(e) })
+	// Register handlers of game rules properties when server classes are known
+	analyser.parser.RegisterEventHandler(func(e events.DataTablesParsed) { analyser.registerGameRulesHandlers() })
+	// Register handler on warmup changed to exclude warmup phases
+	analyser.parser.RegisterEventHandler(func(e events.IsWarmupPeriodChanged) { analyser.handleWarmupChange(e) })
 	// Register handler on round start
 	analyser.parser.RegisterEventHandler(func(e events.RoundStart) { analyser.handleRoundStart(e) })
 	// Register handler on score updated event
@@ -74,6 +84,8 @@ func (analyser *Analyser) registerPlayerEventHandlers() {
 	analyser.parser.RegisterEventHandler(func(e events.ItemPickup) { analyser.dispatchPlayerEvents(e) })
 	analyser.parser.RegisterEventHandler(func(e events.Footstep) { analyser.dispatchPlayerEvents(e) })
 	analyser.parser.RegisterEventHandler(func(e events.PlayerSpottersChanged) { analyser.dispatchPlayerEvents(e) })
+	// Register handler on freeze time end to count rounds participated by players
+	analyser.parser.RegisterEventHandler(func(e events.RoundFreezetimeEnd) { analyser.handleFreezetimeEnd(e) })
 
 	// **************************************************
 	// registered for testing purpose
@@ -136,19 +148,23 @@ func (analyser *Analyser) registerFirstPlayerEventHandlers() {
 	if analyser.isFirstParse {
 		analyser.parser.RegisterEventHandler(func(e events.PlayerHurt) {
 			tick, _ := analyser.getGameTick()
-			analyser.handleHurt(e, tick)
+			analyser.handleCheckHurt(e, tick)
 		})
 		// it is very rare however, there could be some round that no one get hurts and bomb has been
 		// exploded and a team win the round. So we need to record bomb planted event as well in the
 		// first parse to understand a round is valid.
 		analyser.parser.RegisterEventHandler(func(e events.BombPlanted) {
 			tick, _ := analyser.getGameTick()
-			analyser.handleBombPlanted(e, tick)
+			analyser.handleCheckBombPlanted(e, tick)
 		})
 		analyser.parser.RegisterEventHandler(func(e events.Kill) {
 			tick, _ := analyser.getGameTick()
+			analyser.handleCheckWeapon(e.Weapon)
 			analyser.handleCheckKill(e, tick)
 		})
+		// weapons used in a round are recorded to detect knife rounds
+		analyser.parser.RegisterEventHandler(func(e events.WeaponFire) { analyser.handleCheckWeapon(e.Weapon) })
+		analyser.parser.RegisterEventHandler(func(e events.RoundFreezetimeEnd) { analyser.handleCheckEquipment() })
 		analyser.log.Info("Player event handlers have been registered for first parse.")
 
 	}
@@ -163,6 +179,14 @@ func (analyser *Analyser) registerScheduler() {
 		currentTick, err := analyser.getGameTick()
 
 		if !err {
+			// view directions are recorded for crosshair replecament events
+			// handled after the tick they are scheduled
+			if analyser.isSinglePass {
+				keepTicks := int(common.SecondsToTick(analyser.beforeCrosshair, analyser.tickRate)) + 1
+				for _, pplayer := range analyser.players {
+					pplayer.RecordViewDirection(currentTick, keepTicks)
+				}
+			}
 			for i := analyser.lastCheckedTick; i < currentTick; i++ {
 				analyser.customScheduler.checkEvent(i)
 			}
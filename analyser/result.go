@@ -0,0 +1,231 @@
+package analyser
+
+import (
+	"sort"
+
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ############## Analyze results #############
+
+// MatchResult structured result of an analyzed demo file
+type MatchResult struct {
+	// general match information
+	Match MatchInfo `json:"match"`
+	// metadata of the demo and the match
+	Metadata MatchMetadata `json:"metadata"`
+	// records for each valid round
+	Rounds []RoundResult `json:"rounds"`
+	// all detected rounds with their inclusion or exclusion reasons
+	Ledger []LedgerRound `json:"ledger"`
+	// per player feature values
+	Players []PlayerResult `json:"players"`
+	// raw statistics of each player in each valid round
+	RoundPlayers []RoundPlayerResult `json:"round_players"`
+	// statistics of each team
+	Teams []TeamResult `json:"teams"`
+	// names of player features in the order of feature values. In raw count
+	// mode, names of raw values and their denominators follow feature names.
+	FeatureNames []string `json:"feature_names"`
+	// events of valid rounds, empty if timeline is not enabled
+	Timeline []TimelineEvent `json:"-"`
+	// kill positions of the match (used for plotting)
+	KillPositions []*common.KillPosition `json:"-"`
+}
+
+// MatchInfo metadata of an analyzed match
+type MatchInfo struct {
+	// content hash of the demo
+	ID              string `json:"id"`
+	AnalyzerVersion string `json:"analyzer_version"`
+	// map name from demo header
+	MapName string `json:"map_name"`
+	// map name after alias replacement
+	MapAlias    string `json:"map_alias"`
+	RoundPlayed int    `json:"round_played"`
+	TScore      int    `json:"t_score"`
+	CTScore     int    `json:"ct_score"`
+	// clan name of the winner team, empty on draw
+	WinnerTeam string `json:"winner_team"`
+	// round winner bits, zero represent won rounds by the match winner
+	RoundWinners string `json:"round_winners"`
+}
+
+// RoundResult record of a valid round
+type RoundResult struct {
+	Number          int    `json:"number"`
+	StartTick       int    `json:"start_tick"`
+	EndTick         int    `json:"end_tick"`
+	OfficialEndTick int    `json:"official_end_tick"`
+	TScore          int    `json:"t_score"`
+	CTScore         int    `json:"ct_score"`
+	Winner          string `json:"winner"`
+}
+
+// RoundPlayerResult raw statistics of a player in a valid round
+type RoundPlayerResult struct {
+	Round        int    `json:"round"`
+	SteamID      int64  `json:"steam_id"`
+	Name         string `json:"name"`
+	Side         string `json:"side"`
+	RoundType    string `json:"round_type"`
+	Kills        uint   `json:"kills"`
+	Deaths       uint   `json:"deaths"`
+	Assists      uint   `json:"assists"`
+	Damage       uint   `json:"damage"`
+	FirstKill    bool   `json:"first_kill"`
+	FlashAssists uint   `json:"flash_assists"`
+	MoneySaved   int    `json:"money_saved"`
+	KAST         bool   `json:"kast"`
+	Survived     bool   `json:"survived"`
+	// side of the round winner
+	Winner string `json:"winner"`
+	// clan name of the round winner
+	WinnerTeam string `json:"winner_team"`
+}
+
+// PlayerResult feature values of a player
+type PlayerResult struct {
+	SteamID int64  `json:"steam_id"`
+	Name    string `json:"name"`
+	Team    string `json:"team"`
+	Side    string `json:"side"`
+	// 1 if player team won the match or match is draw
+	Won int `json:"won"`
+	// number of rounds the player was alive at freeze time end
+	RoundsPlayed int       `json:"rounds_played"`
+	Features     []float32 `json:"features"`
+}
+
+// buildMatchResult create match result from current analyser state
+func (analyser *Analyser) buildMatchResult() *MatchResult {
+	mapnameAlias := viper.GetStringMapString("mapnameAlias")
+	mapname := analyser.mapName
+	if newMapname, ok := mapnameAlias[mapname]; ok {
+		analyser.log.WithFields(logging.Fields{
+			"new name": newMapname,
+			"old name": mapname,
+		}).Info("Mapname changed: ")
+		mapname = newMapname
+	}
+
+	gs := analyser.parser.GameState()
+	teamWon := analyser.getWinnerTeam()
+	var winnerName string
+	if teamWon != p_common.TeamUnassigned {
+		winnerName = gs.Team(teamWon).ClanName
+	}
+
+	featureNames := common.FeatureNames()
+	if analyser.isRawCounts {
+		featureNames = append(featureNames, common.RawFeatureNames()...)
+	}
+
+	result := &MatchResult{
+		Match: MatchInfo{
+			ID:              analyser.demoHash,
+			AnalyzerVersion: viper.GetString("output.analyzer_version"),
+			MapName:         analyser.mapName,
+			MapAlias:        mapname,
+			RoundPlayed:     analyser.roundPlayed,
+			TScore:          analyser.tScore,
+			CTScore:         analyser.ctScore,
+			WinnerTeam:      winnerName,
+			RoundWinners:    analyser.createRoundString(winnerName),
+		},
+		RoundPlayers:  analyser.roundPlayers,
+		Timeline:      analyser.timeline,
+		FeatureNames:  featureNames,
+		KillPositions: analyser.killPositions,
+	}
+	sort.SliceStable(result.RoundPlayers, func(i, j int) bool {
+		if result.RoundPlayers[i].Round != result.RoundPlayers[j].Round {
+			return result.RoundPlayers[i].Round < result.RoundPlayers[j].Round
+		}
+		return result.RoundPlayers[i].SteamID < result.RoundPlayers[j].SteamID
+	})
+
+	// rounds
+	var roundNumbers []int
+	for roundNumber := range analyser.validRounds {
+		roundNumbers = append(roundNumbers, roundNumber)
+	}
+	sort.Ints(roundNumbers)
+	for _, roundNumber := range roundNumbers {
+		round := analyser.validRounds[roundNumber]
+		result.Rounds = append(result.Rounds, RoundResult{
+			Number:          roundNumber,
+			StartTick:       round.StartTick,
+			EndTick:         round.EndTick,
+			OfficialEndTick: round.OfficialEndTick,
+			TScore:          round.TScore,
+			CTScore:         round.CTScore,
+			Winner:          analyser.roundWinners[roundNumber],
+		})
+	}
+
+	// players
+	players := analyser.getAllPlayers()
+	// rounds participated can not be counted for a demo without freeze time end events
+	if !analyser.isParticipationCounted {
+		for _, currPlayer := range players {
+			currPlayer.SetRoundsParticipated(analyser.roundPlayed)
+		}
+	}
+	var validPlayers []*common.PPlayer
+	for _, currPlayer := range players {
+		if !(analyser.checkTeamValidity(currPlayer.Team)) {
+			analyser.log.WithFields(logging.Fields{
+				"name":     currPlayer.Name,
+				"team":     currPlayer.Team,
+				"old team": currPlayer.GetOldTeam(),
+			}).Info("Player team or old team is wrong ")
+			continue
+		} else if currPlayer.GetNumKills() <= 0 && currPlayer.GetNumDeaths() <= 0 {
+			analyser.log.WithFields(logging.Fields{
+				"name":      currPlayer.Name,
+				"team":      currPlayer.Team,
+				"num kill":  currPlayer.GetNumKills(),
+				"num death": currPlayer.GetNumDeaths(),
+			}).Info("Player has wrong stat ")
+			continue
+		}
+
+		winLabel := 0
+		// if there is equality or player team won set to 1
+		if teamWon == p_common.TeamUnassigned || currPlayer.Team == teamWon {
+			winLabel = 1
+		}
+
+		var teamName string
+		if currPlayer.TeamState != nil {
+			teamName = currPlayer.TeamState.ClanName
+		}
+
+		features := currPlayer.FeatureValues()
+		if analyser.isRawCounts {
+			features = append(features, currPlayer.RawFeatureValues(analyser.roundPlayed)...)
+		}
+
+		result.Players = append(result.Players, PlayerResult{
+			SteamID:      currPlayer.SteamID,
+			Name:         analyser.playerName(currPlayer),
+			Team:         teamName,
+			Side:         common.GetSideString(currPlayer.Team),
+			Won:          winLabel,
+			RoundsPlayed: currPlayer.GetRoundsParticipated(),
+			Features:     features,
+		})
+		validPlayers = append(validPlayers, currPlayer)
+	}
+
+	// teams
+	result.Teams = analyser.buildTeamResults(validPlayers, analyser.teamClanNames())
+	result.Metadata = analyser.buildMatchMetadata(result.Match)
+	result.Ledger = analyser.buildRoundLedger()
+
+	return result
+}
@@ -0,0 +1,111 @@
+package analyser
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RoundLedgerSuffix suffix of the round ledger written next to a result file
+const RoundLedgerSuffix = "_ledger.csv"
+
+// ReasonValid round has passed validity checks of the first parsing
+const ReasonValid = "valid"
+
+// round ledger csv header
+var roundLedgerHeader = []string{"round", "start_tick", "end_tick", "official_end_tick", "t_score", "ct_score", "included", "reason"}
+
+// LedgerRound a round detected in the demo with its inclusion or exclusion reason
+type LedgerRound struct {
+	// number of the round in the match, for an excluded round the
+	// number it would have, zero for a warmup phase
+	Round           int  `json:"round"`
+	StartTick       int  `json:"start_tick"`
+	EndTick         int  `json:"end_tick"`
+	OfficialEndTick int  `json:"official_end_tick"`
+	TScore          int  `json:"t_score"`
+	CTScore         int  `json:"ct_score"`
+	Included        bool `json:"included"`
+	// reason of including or excluding the round
+	Reason string `json:"reason"`
+}
+
+// buildRoundLedger list all valid and excluded rounds in the order of their start ticks
+func (analyser *Analyser) buildRoundLedger() []LedgerRound {
+	var ledger []LedgerRound
+	for roundNumber, round := range analyser.validRounds {
+		reason := ReasonValid
+		if analyser.includedRounds[round.StartTick] {
+			reason = ReasonOverrideInclude
+		}
+		ledger = append(ledger, LedgerRound{
+			Round:           roundNumber,
+			StartTick:       round.StartTick,
+			EndTick:         round.EndTick,
+			OfficialEndTick: round.OfficialEndTick,
+			TScore:          round.TScore,
+			CTScore:         round.CTScore,
+			Included:        true,
+			Reason:          reason,
+		})
+	}
+	for _, excluded := range analyser.excludedRounds {
+		ledger = append(ledger, LedgerRound{
+			Round:           excluded.Round,
+			StartTick:       excluded.StartTick,
+			EndTick:         excluded.EndTick,
+			OfficialEndTick: excluded.OfficialEndTick,
+			TScore:          excluded.TScore,
+			CTScore:         excluded.CTScore,
+			Reason:          excluded.Reason,
+		})
+	}
+	sort.SliceStable(ledger, func(i, j int) bool {
+		if ledger[i].StartTick != ledger[j].StartTick {
+			return ledger[i].StartTick < ledger[j].StartTick
+		}
+		return ledger[i].EndTick < ledger[j].EndTick
+	})
+
+	return ledger
+}
+
+// RoundLedgerPath get path of the round ledger of a result file
+func RoundLedgerPath(outPath string) string {
+	return strings.TrimSuffix(outPath, filepath.Ext(outPath)) + RoundLedgerSuffix
+}
+
+// WriteRoundLedger write a row for each round in the ledger of a match result to given path
+func WriteRoundLedger(path string, result *MatchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(roundLedgerHeader); err != nil {
+		return err
+	}
+	for _, round := range result.Ledger {
+		record := []string{
+			strconv.Itoa(round.Round),
+			strconv.Itoa(round.StartTick),
+			strconv.Itoa(round.EndTick),
+			strconv.Itoa(round.OfficialEndTick),
+			strconv.Itoa(round.TScore),
+			strconv.Itoa(round.CTScore),
+			strconv.FormatBool(round.Included),
+			round.Reason,
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,69 @@
+package analyser
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RoundTableSuffix suffix of the per round player table written next to a result file
+const RoundTableSuffix = "_rounds.csv"
+
+// round table csv header
+var roundTableHeader = []string{"round", "steam_id", "name", "side", "round_type", "kills", "deaths", "assists",
+	"damage", "first_kill", "flash_assists", "money_saved", "kast", "survived", "winner", "winner_team"}
+
+// RoundTablePath get path of the round table of a result file
+func RoundTablePath(outPath string) string {
+	return strings.TrimSuffix(outPath, filepath.Ext(outPath)) + RoundTableSuffix
+}
+
+// WriteRoundTable write a row for each player in each valid round of a match result to given path
+func WriteRoundTable(path string, result *MatchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(roundTableHeader); err != nil {
+		return err
+	}
+	for _, row := range result.RoundPlayers {
+		record := []string{
+			strconv.Itoa(row.Round),
+			strconv.FormatInt(row.SteamID, 10),
+			row.Name,
+			row.Side,
+			row.RoundType,
+			strconv.FormatUint(uint64(row.Kills), 10),
+			strconv.FormatUint(uint64(row.Deaths), 10),
+			strconv.FormatUint(uint64(row.Assists), 10),
+			strconv.FormatUint(uint64(row.Damage), 10),
+			boolFlag(row.FirstKill),
+			strconv.FormatUint(uint64(row.FlashAssists), 10),
+			strconv.Itoa(row.MoneySaved),
+			boolFlag(row.KAST),
+			boolFlag(row.Survived),
+			row.Winner,
+			row.WinnerTeam,
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// boolFlag return 1 for true, 0 otherwise
+func boolFlag(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,178 @@
+package analyser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	utils "github.com/quancore/demoanalyzer-go/utils"
+	"github.com/spf13/viper"
+)
+
+// MatchRules rules of the match format
+type MatchRules struct {
+	// number of rounds of normal time (mp_maxrounds)
+	MaxRounds int `json:"max_rounds"`
+	// number of rounds of each overtime (mp_overtime_maxrounds)
+	OvertimeMaxRounds int `json:"overtime_max_rounds"`
+	// overtime is played on a tie, otherwise match ends with a draw (mp_overtime_enable)
+	OvertimeEnabled bool `json:"overtime_enabled"`
+	// match ends as soon as a team can not be caught up (mp_match_can_clinch)
+	CanClinch bool `json:"can_clinch"`
+}
+
+// cvars of match rules and their config keys
+var ruleCvars = map[string]string{
+	"mp_maxrounds":          "max_rounds",
+	"mp_overtime_maxrounds": "overtime_max_rounds",
+	"mp_overtime_enable":    "overtime_enable",
+	"mp_match_can_clinch":   "can_clinch",
+}
+
+// newMatchRules create rules of a competitive MR15 match with the
+// overrides in the match_rules section of the config
+func newMatchRules() MatchRules {
+	rules := MatchRules{MaxRounds: 30, OvertimeMaxRounds: 6, OvertimeEnabled: true, CanClinch: true}
+	for _, key := range ruleCvars {
+		if viper.IsSet("match_rules." + key) {
+			rules.set(key, viper.GetString("match_rules."+key))
+		}
+	}
+
+	return rules
+}
+
+// ruleOverrides overrides of match rules in the config as a sorted list of key=value
+func ruleOverrides() string {
+	var overrides []string
+	for _, key := range ruleCvars {
+		if viper.IsSet("match_rules." + key) {
+			overrides = append(overrides, key+"="+viper.GetString("match_rules."+key))
+		}
+	}
+	sort.Strings(overrides)
+
+	return strings.Join(overrides, ",")
+}
+
+// set set a rule by its config key, invalid values are ignored
+func (rules *MatchRules) set(key, value string) {
+	switch key {
+	case "max_rounds", "overtime_max_rounds":
+		// rounds are divided into halves
+		if n, err := strconv.Atoi(value); err == nil && n >= 2 {
+			if key == "max_rounds" {
+				rules.MaxRounds = n
+			} else {
+				rules.OvertimeMaxRounds = n
+			}
+		}
+	case "overtime_enable", "can_clinch":
+		if b, err := strconv.ParseBool(value); err == nil {
+			if key == "overtime_enable" {
+				rules.OvertimeEnabled = b
+			} else {
+				rules.CanClinch = b
+			}
+		}
+	}
+}
+
+// setRuleCvar set a rule from its cvar unless the rule is set in the config
+func (analyser *Analyser) setRuleCvar(name, value string) {
+	key, ok := ruleCvars[name]
+	if !ok || viper.IsSet("match_rules."+key) {
+		return
+	}
+	analyser.rules.set(key, value)
+}
+
+// halfRounds number of rounds of a normal time half
+func (rules MatchRules) halfRounds() int { return rules.MaxRounds / 2 }
+
+// winRounds number of rounds to win the match in normal time
+func (rules MatchRules) winRounds() int { return rules.MaxRounds/2 + 1 }
+
+// overtimeHalfRounds number of rounds of an overtime half
+func (rules MatchRules) overtimeHalfRounds() int { return rules.OvertimeMaxRounds / 2 }
+
+// minRounds minimum number of rounds of a finished match
+func (rules MatchRules) minRounds() int {
+	if rules.CanClinch {
+		return rules.winRounds()
+	}
+
+	return rules.MaxRounds
+}
+
+// isPistolRound check whether the round is the first round of a normal time half
+func (rules MatchRules) isPistolRound(roundNumber int) bool {
+	return roundNumber >= 1 && roundNumber <= rules.MaxRounds && (roundNumber-1)%rules.halfRounds() == 0
+}
+
+// isHalfStart check whether given number of played rounds starts a new half
+func (rules MatchRules) isHalfStart(roundPlayed int) bool {
+	if roundPlayed == rules.halfRounds() || roundPlayed == rules.MaxRounds {
+		return true
+	}
+	overtimeRounds := roundPlayed - rules.MaxRounds
+
+	return rules.OvertimeEnabled && overtimeRounds > 0 && overtimeRounds%rules.overtimeHalfRounds() == 0
+}
+
+// isSideSwapped check whether teams play on the opposite of their starting side in given round
+func (rules MatchRules) isSideSwapped(roundNumber int) bool {
+	swapped := false
+	for roundPlayed := 1; roundPlayed < roundNumber; roundPlayed++ {
+		if rules.isHalfStart(roundPlayed) {
+			swapped = !swapped
+		}
+	}
+
+	return swapped
+}
+
+// isDraw check whether the match ends with a draw for given scores
+func (rules MatchRules) isDraw(tScore, ctScore int) bool {
+	return !rules.OvertimeEnabled && tScore == ctScore && tScore+ctScore == rules.MaxRounds
+}
+
+// numOvertime number of overtimes played for given number of played rounds
+func (rules MatchRules) numOvertime(roundPlayed int) int {
+	overtimeRounds := roundPlayed - rules.MaxRounds
+	if overtimeRounds <= 0 {
+		return 0
+	}
+
+	return (overtimeRounds + rules.OvertimeMaxRounds - 1) / rules.OvertimeMaxRounds
+}
+
+// isMatchOver check whether match should end for given scores. Second value
+// is true if a team has won in normal time or normal time is over.
+func (rules MatchRules) isMatchOver(tScore, ctScore int) (bool, bool) {
+	win := rules.winRounds()
+	overtimeRounds := tScore + ctScore - rules.MaxRounds
+	absDiff := utils.Abs(ctScore - tScore)
+
+	// normal time
+	if overtimeRounds < 0 {
+		if (ctScore >= win) != (tScore >= win) {
+			return rules.CanClinch, true
+		}
+		return false, false
+	}
+	if overtimeRounds == 0 {
+		return absDiff > 0 || !rules.OvertimeEnabled, true
+	}
+	if !rules.OvertimeEnabled {
+		return true, true
+	}
+
+	// each overtime starts with a tie, so score difference is the difference in the overtime
+	x := overtimeRounds % rules.OvertimeMaxRounds
+	if x == 0 {
+		return absDiff > 0, true
+	}
+	// a team has won more than half of the overtime rounds
+	return rules.CanClinch && x > rules.overtimeHalfRounds() && absDiff >= rules.OvertimeMaxRounds+2-x, true
+}
@@ -0,0 +1,97 @@
+package analyser
+
+import "testing"
+
+var (
+	mr15      = MatchRules{MaxRounds: 30, OvertimeMaxRounds: 6, OvertimeEnabled: true, CanClinch: true}
+	mr12      = MatchRules{MaxRounds: 24, OvertimeMaxRounds: 6, OvertimeEnabled: true, CanClinch: true}
+	drawRules = MatchRules{MaxRounds: 30, OvertimeMaxRounds: 6, OvertimeEnabled: false, CanClinch: true}
+	noClinch  = MatchRules{MaxRounds: 30, OvertimeMaxRounds: 6, OvertimeEnabled: true, CanClinch: false}
+)
+
+// TestIsMatchOver test match end for scores of normal time and overtime
+func TestIsMatchOver(t *testing.T) {
+	cases := []struct {
+		rules           MatchRules
+		tScore, ctScore int
+		over, normalEnd bool
+	}{
+		{mr15, 15, 10, false, false},
+		{mr15, 16, 10, true, true},
+		{mr15, 10, 16, true, true},
+		{mr15, 15, 15, false, true},
+		{mr15, 18, 15, false, true},
+		{mr15, 19, 15, true, true},
+		{mr15, 19, 16, true, true},
+		{mr15, 18, 18, false, true},
+		{mr15, 19, 17, true, true},
+		{mr12, 13, 5, true, true},
+		{mr12, 12, 11, false, false},
+		{mr12, 12, 12, false, true},
+		{mr12, 16, 13, true, true},
+		{drawRules, 15, 15, true, true},
+		{drawRules, 16, 10, true, true},
+		{noClinch, 16, 5, false, true},
+		{noClinch, 16, 14, true, true},
+		{noClinch, 19, 15, false, true},
+		{noClinch, 20, 16, true, true},
+	}
+	for _, c := range cases {
+		over, normalEnd := c.rules.isMatchOver(c.tScore, c.ctScore)
+		if over != c.over || normalEnd != c.normalEnd {
+			t.Errorf("MR%d %d-%d: expected (%v, %v), got (%v, %v)", c.rules.halfRounds(), c.tScore, c.ctScore,
+				c.over, c.normalEnd, over, normalEnd)
+		}
+	}
+}
+
+// TestIsHalfStart test half starts of normal time and overtime
+func TestIsHalfStart(t *testing.T) {
+	cases := []struct {
+		rules       MatchRules
+		roundPlayed int
+		expected    bool
+	}{
+		{mr15, 14, false},
+		{mr15, 15, true},
+		{mr15, 30, true},
+		{mr15, 33, true},
+		{mr15, 34, false},
+		{mr15, 36, true},
+		{mr12, 12, true},
+		{mr12, 15, false},
+		{mr12, 24, true},
+		{mr12, 27, true},
+		{drawRules, 15, true},
+		{drawRules, 33, false},
+	}
+	for _, c := range cases {
+		if actual := c.rules.isHalfStart(c.roundPlayed); actual != c.expected {
+			t.Errorf("MR%d round %d: expected %v, got %v", c.rules.halfRounds(), c.roundPlayed, c.expected, actual)
+		}
+	}
+}
+
+// TestIsPistolRound test pistol rounds are the first rounds of normal time halves
+func TestIsPistolRound(t *testing.T) {
+	cases := []struct {
+		rules       MatchRules
+		roundNumber int
+		expected    bool
+	}{
+		{mr15, 0, false},
+		{mr15, 1, true},
+		{mr15, 2, false},
+		{mr15, 15, false},
+		{mr15, 16, true},
+		{mr15, 31, false},
+		{mr12, 13, true},
+		{mr12, 16, false},
+		{mr12, 25, false},
+	}
+	for _, c := range cases {
+		if actual := c.rules.isPistolRound(c.roundNumber); actual != c.expected {
+			t.Errorf("MR%d round %d: expected %v, got %v", c.rules.halfRounds(), c.roundNumber, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package analyser
+
+import (
+	"context"
+	"fmt"
+
+	dem "github.com/markus-wa/demoinfocs-golang"
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	common "github.com/quancore/demoanalyzer-go/common"
+	logging "github.com/sirupsen/logrus"
+)
+
+// Single pass analyze checks validity of rounds and collects player statistics
+// in the same parsing. Statistics of each round are staged and committed when
+// the round has officially ended or discarded when the round has been cancelled.
+
+// analyzeSinglePass parse demofile once and return the result of the match
+func (analyser *Analyser) analyzeSinglePass(ctx context.Context) (*MatchResult, error) {
+	analyser.log.Info("Analyzing in a single pass")
+	analyser.isFirstParse = true
+	if err := analyser.initMapData(); err != nil {
+		return nil, err
+	}
+	analyser.registerNetMessageHandlers()
+	analyser.registerMatchEventHandlers()
+	// handlers checking validity of a round have to be registered
+	// before the handlers collecting statistics
+	analyser.registerFirstPlayerEventHandlers()
+	analyser.registerPlayerEventHandlers()
+	analyser.registerScheduler()
+
+	// sometimes demo files enden unexpectedly however, it is not
+	// important if the match has already ended
+	if err := analyser.parseFrames(ctx, FirstPass); err == dem.ErrUnexpectedEndOfDemo && analyser.matchEnded {
+		analyser.log.Info("Demo file ended unexpectedly however, match has been finished")
+	} else if err != nil {
+		return nil, analyser.parseError(err)
+	}
+
+	if analyser.err != nil {
+		return nil, analyser.err
+	}
+	if err := analyser.hashDemo(); err != nil {
+		return nil, newAnalyzeError(ErrDemoCorrupted, err)
+	}
+	analyser.settleStagedRound()
+	if len(analyser.validRounds) == 0 {
+		return nil, newAnalyzeError(ErrNoValidRounds, nil)
+	}
+	// a demo cut off before the end of the match has only a part of the rounds
+	if !analyser.matchEnded {
+		return nil, newAnalyzeError(ErrMatchNotFinished,
+			fmt.Errorf("demo ended after %d rounds, score %d-%d", analyser.roundPlayed, analyser.tScore, analyser.ctScore))
+	}
+
+	analyser.log.WithFields(logging.Fields{
+		"total round played": analyser.roundPlayed,
+		"team terrorist":     analyser.tScore,
+		"team ct terrorist":  analyser.ctScore,
+	}).Info("Match is over. ")
+	if !analyser.finishMatch() {
+		return nil, analyser.err
+	}
+
+	return analyser.result, nil
+}
+
+// stageRound start staging statistics of a new round
+func (analyser *Analyser) stageRound(tick int) {
+	analyser.settleStagedRound()
+
+	for _, pplayer := range analyser.players {
+		pplayer.StageRound()
+	}
+	for _, disconnected := range analyser.disconnectedPlayers {
+		disconnected.Player.StageRound()
+	}
+	analyser.stagedKillPositions = len(analyser.killPositions)
+	analyser.isRoundStaged = true
+	analyser.isStagedRoundValid = false
+
+	analyser.log.WithFields(logging.Fields{
+		"tick":         tick,
+		"round number": analyser.currentRound(),
+	}).Debug("Round has been staged")
+}
+
+// endStagedRound handle valid end of the staged round
+func (analyser *Analyser) endStagedRound(winnerT, loserT p_common.Team, winnerName string, tick int) {
+	if !analyser.isRoundStaged {
+		return
+	}
+	analyser.handleSpecialRound(winnerT, loserT, tick)
+
+	// set winner team to use in round official end
+	analyser.winnerTeam = winnerT
+	// record winner of the round
+	analyser.roundWinners[analyser.roundPlayed] = winnerName
+	analyser.isStagedRoundValid = true
+}
+
+// commitStagedRound finish the staged round with a valid end
+// and keep statistics collected in the round
+func (analyser *Analyser) commitStagedRound(tick int) {
+	if !analyser.isRoundStaged || !analyser.isStagedRoundValid {
+		return
+	}
+
+	// calculate round duration
+	roundDurationSecond := common.TickToSeconds(tick-analyser.roundStart, analyser.tickRate)
+	analyser.notifyRoundEnd(analyser.roundPlayed, analyser.winnerTeam, roundDurationSecond)
+	analyser.handleKAST(tick)
+	// if there is a winner handle clutch as well
+	if analyser.winnerTeam == p_common.TeamTerrorists || analyser.winnerTeam == p_common.TeamCounterTerrorists {
+		analyser.handleClutchSituation(analyser.winnerTeam, tick)
+	}
+	analyser.recordRoundPlayers()
+	analyser.recordTeamRound()
+	analyser.commitRoundTimeline(tick)
+	// map occupancy is calculated at the end of the round
+	if analyser.navigator != nil {
+		mapControl{eventCommon: eventCommon{analyser: analyser}}.postEventHandler()
+	}
+
+	for _, pplayer := range analyser.players {
+		pplayer.CommitRound()
+	}
+	for _, disconnected := range analyser.disconnectedPlayers {
+		disconnected.Player.CommitRound()
+	}
+	analyser.customScheduler.reset()
+	analyser.isRoundStaged = false
+	analyser.isStagedRoundValid = false
+
+	analyser.log.WithFields(logging.Fields{
+		"tick":               tick,
+		"round number":       analyser.roundPlayed,
+		"round time seconds": roundDurationSecond.Seconds(),
+	}).Info("Staged round has been committed")
+}
+
+// discardStagedRound roll back statistics collected in the staged round
+func (analyser *Analyser) discardStagedRound(tick int) {
+	if !analyser.isRoundStaged {
+		return
+	}
+
+	for _, pplayer := range analyser.players {
+		pplayer.DiscardRound()
+	}
+	for _, disconnected := range analyser.disconnectedPlayers {
+		disconnected.Player.DiscardRound()
+	}
+	if analyser.stagedKillPositions <= len(analyser.killPositions) {
+		analyser.killPositions = analyser.killPositions[:analyser.stagedKillPositions]
+	}
+	analyser.roundTimeline = nil
+	analyser.customScheduler.reset()
+	analyser.isRoundStaged = false
+	analyser.isStagedRoundValid = false
+
+	analyser.log.WithFields(logging.Fields{
+		"tick":       tick,
+		"start tick": analyser.roundStart,
+	}).Info("Staged round has been discarded")
+}
+
+// settleStagedRound commit the staged round if it has a valid end,
+// otherwise discard it
+func (analyser *Analyser) settleStagedRound() {
+	if !analyser.isRoundStaged {
+		return
+	}
+	if analyser.isStagedRoundValid {
+		// there is no official end for the round
+		analyser.commitStagedRound(analyser.roundEnd)
+	} else {
+		tick, _ := analyser.getGameTick()
+		analyser.discardStagedRound(tick)
+	}
+}
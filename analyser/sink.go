@@ -0,0 +1,190 @@
+package analyser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// output formats of match results
+const (
+	// FormatText legacy text output with a metadata line before the header
+	FormatText = "text"
+	// FormatCSV RFC 4180 csv with a header and a row per player
+	FormatCSV = "csv"
+	// FormatJSON a json document per match result
+	FormatJSON = "json"
+	// FormatJSONLines a json object per line for each match result
+	FormatJSONLines = "jsonl"
+)
+
+// StdoutPath output path writing to standard output
+const StdoutPath = "-"
+
+// OutputSink destination of analyzed match results
+type OutputSink interface {
+	// Write write a match result to the sink
+	Write(result *MatchResult) error
+	// Close flush and close the sink
+	Close() error
+}
+
+// sinkFormat an output format registered by another package
+type sinkFormat struct {
+	ext string
+	// whether results of all demos are written into a single database file
+	isDatabase bool
+	newSink    func(path string) (OutputSink, error)
+}
+
+// registered output formats
+var sinkFormats = make(map[string]*sinkFormat)
+
+// RegisterSink register an output format so that NewSink can create its sinks.
+// If isDatabase is set, results of many demos are written into a single file.
+func RegisterSink(format, ext string, isDatabase bool, newSink func(path string) (OutputSink, error)) {
+	sinkFormats[format] = &sinkFormat{ext: ext, isDatabase: isDatabase, newSink: newSink}
+}
+
+// IsDatabaseFormat report whether results of many demos are written into a single file with the format
+func IsDatabaseFormat(format string) bool {
+	registered, ok := sinkFormats[format]
+	return ok && registered.isDatabase
+}
+
+// SinkExt return file extension of an output format
+func SinkExt(format string) string {
+	if registered, ok := sinkFormats[format]; ok {
+		return registered.ext
+	}
+	switch format {
+	case FormatCSV:
+		return ".csv"
+	case FormatJSON:
+		return ".json"
+	case FormatJSONLines:
+		return ".jsonl"
+	}
+
+	return ".txt"
+}
+
+// NewSink create an output sink with given format writing to path.
+// If path is StdoutPath, results are written to standard output.
+func NewSink(format, path string) (OutputSink, error) {
+	if registered, ok := sinkFormats[format]; ok {
+		if path == StdoutPath {
+			return nil, fmt.Errorf("output format %q can not be written to standard output", format)
+		}
+		return registered.newSink(path)
+	}
+
+	var w io.WriteCloser = nopCloser{os.Stdout}
+	if path != StdoutPath {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+
+	switch format {
+	case FormatText, "":
+		return &textSink{w: w}, nil
+	case FormatCSV:
+		return &csvSink{w: w, csvWriter: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return &jsonSink{w: w, enc: enc}, nil
+	case FormatJSONLines:
+		return &jsonSink{w: w, enc: json.NewEncoder(w)}, nil
+	}
+	w.Close()
+
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// textSink write match results in legacy text format
+type textSink struct {
+	w io.WriteCloser
+}
+
+// Write write a match result
+func (s *textSink) Write(result *MatchResult) error {
+	_, err := io.WriteString(s.w, textMatchResult(result))
+	return err
+}
+
+// Close close underlying writer
+func (s *textSink) Close() error { return s.w.Close() }
+
+// csvSink write player features as csv rows, header is written once
+type csvSink struct {
+	w           io.WriteCloser
+	csvWriter   *csv.Writer
+	wroteHeader bool
+}
+
+// Write write a row for each player of a match result
+func (s *csvSink) Write(result *MatchResult) error {
+	if !s.wroteHeader {
+		header := append([]string{"SteamID", "Name"}, result.FeatureNames...)
+		if err := s.csvWriter.Write(append(header, "Won", "RoundsParticipated")); err != nil {
+			return err
+		}
+		s.wroteHeader = true
+	}
+	for _, player := range result.Players {
+		row := []string{strconv.FormatInt(player.SteamID, 10), player.Name}
+		for _, value := range player.Features {
+			row = append(row, strconv.FormatFloat(float64(value), 'f', -1, 32))
+		}
+		if err := s.csvWriter.Write(append(row, strconv.Itoa(player.Won), strconv.Itoa(player.RoundsPlayed))); err != nil {
+			return err
+		}
+	}
+	s.csvWriter.Flush()
+
+	return s.csvWriter.Error()
+}
+
+// Close close underlying writer
+func (s *csvSink) Close() error { return s.w.Close() }
+
+// jsonSink encode each match result as a json value
+type jsonSink struct {
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// Write write a match result
+func (s *jsonSink) Write(result *MatchResult) error { return s.enc.Encode(result) }
+
+// Close close underlying writer
+func (s *jsonSink) Close() error { return s.w.Close() }
+
+// nopCloser writer which is not closed by a sink
+type nopCloser struct {
+	io.Writer
+}
+
+// Close do nothing
+func (nopCloser) Close() error { return nil }
+
+// WriteResult write a match result to path with given format
+func WriteResult(format, path string, result *MatchResult) error {
+	sink, err := NewSink(format, path)
+	if err != nil {
+		return err
+	}
+	if err := sink.Write(result); err != nil {
+		sink.Close()
+		return err
+	}
+
+	return sink.Close()
+}
@@ -0,0 +1,221 @@
+package analyser
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	common "github.com/quancore/demoanalyzer-go/common"
+)
+
+// TeamTableSuffix suffix of the team table written next to a result file
+const TeamTableSuffix = "_teams.csv"
+
+// team table csv header
+var teamTableHeader = []string{"name", "side", "score", "t_rounds_won", "ct_rounds_won", "pistol_rounds_won",
+	"eco_rounds_won", "force_buy_rounds_won", "damage", "first_kills", "clutches_won", "trades", "av_occupied_area", "won"}
+
+// TeamResult statistics of a team in a match
+type TeamResult struct {
+	// clan name of the team
+	Name string `json:"name"`
+	// side of the team at the end of the match
+	Side              string `json:"side"`
+	Score             int    `json:"score"`
+	TRoundsWon        int    `json:"t_rounds_won"`
+	CTRoundsWon       int    `json:"ct_rounds_won"`
+	PistolRoundsWon   int    `json:"pistol_rounds_won"`
+	EcoRoundsWon      int    `json:"eco_rounds_won"`
+	ForceBuyRoundsWon int    `json:"force_buy_rounds_won"`
+	Damage            uint   `json:"damage"`
+	FirstKills        uint   `json:"first_kills"`
+	ClutchesWon       uint   `json:"clutches_won"`
+	Trades            uint   `json:"trades"`
+	// average map area occupied by the team in a round
+	AvOccupiedArea float32 `json:"av_occupied_area"`
+	// 1 if the team won the match or match is draw
+	Won int `json:"won"`
+}
+
+// teamRounds statistics of a team collected at the end of each valid round
+type teamRounds struct {
+	tRoundsWon        int
+	ctRoundsWon       int
+	pistolRoundsWon   int
+	ecoRoundsWon      int
+	forceBuyRoundsWon int
+	// rounds won in the first half of normal time
+	firstHalfRoundsWon int
+	occupiedArea       float32
+	numAreaRounds      int
+}
+
+// startingSide get the starting side of the team playing on given side in given round.
+// Clan names can be empty or equal, so teams are identified by their starting side.
+func (analyser *Analyser) startingSide(side p_common.Team, roundNumber int) p_common.Team {
+	if !analyser.rules.isSideSwapped(roundNumber) {
+		return side
+	}
+	if side == p_common.TeamTerrorists {
+		return p_common.TeamCounterTerrorists
+	}
+
+	return p_common.TeamTerrorists
+}
+
+// getTeamRounds get round statistics of the team playing on given side in current round
+func (analyser *Analyser) getTeamRounds(side p_common.Team) *teamRounds {
+	startingSide := analyser.startingSide(side, analyser.roundPlayed)
+	rounds, ok := analyser.teamRounds[startingSide]
+	if !ok {
+		rounds = &teamRounds{}
+		analyser.teamRounds[startingSide] = rounds
+	}
+
+	return rounds
+}
+
+// recordTeamRound record the winner team of the ended round
+func (analyser *Analyser) recordTeamRound() {
+	var roundType common.RoundType
+	switch analyser.winnerTeam {
+	case p_common.TeamTerrorists:
+		roundType = analyser.currentTRoundType
+	case p_common.TeamCounterTerrorists:
+		roundType = analyser.currentCTRoundType
+	default:
+		return
+	}
+
+	rounds := analyser.getTeamRounds(analyser.winnerTeam)
+	if analyser.winnerTeam == p_common.TeamTerrorists {
+		rounds.tRoundsWon++
+	} else {
+		rounds.ctRoundsWon++
+	}
+	if analyser.roundPlayed <= analyser.rules.halfRounds() {
+		rounds.firstHalfRoundsWon++
+	}
+	switch roundType {
+	case common.PistolRound:
+		rounds.pistolRoundsWon++
+	case common.EcoRound:
+		rounds.ecoRoundsWon++
+	case common.ForceBuyRound:
+		rounds.forceBuyRoundsWon++
+	}
+}
+
+// recordTeamArea record map area occupied by the team of a side in a round
+func (analyser *Analyser) recordTeamArea(side p_common.Team, area float32) {
+	rounds := analyser.getTeamRounds(side)
+	rounds.occupiedArea += area
+	rounds.numAreaRounds++
+}
+
+// teamClanNames get clan names of teams by their current side
+func (analyser *Analyser) teamClanNames() map[p_common.Team]string {
+	gs := analyser.parser.GameState()
+	clanNames := make(map[p_common.Team]string)
+	for _, side := range []p_common.Team{p_common.TeamTerrorists, p_common.TeamCounterTerrorists} {
+		if teamState := gs.Team(side); teamState != nil {
+			clanNames[side] = teamState.ClanName
+		}
+	}
+
+	return clanNames
+}
+
+// buildTeamResults create team statistics from round statistics and player results,
+// a team is only included if its clan name is given for its current side
+func (analyser *Analyser) buildTeamResults(players []*common.PPlayer, clanNames map[p_common.Team]string) []TeamResult {
+	teamWon := analyser.getWinnerTeam()
+
+	var teams []TeamResult
+	for _, side := range []p_common.Team{p_common.TeamTerrorists, p_common.TeamCounterTerrorists} {
+		clanName, ok := clanNames[side]
+		if !ok {
+			continue
+		}
+		team := TeamResult{Name: clanName, Side: common.GetSideString(side)}
+		if side == p_common.TeamTerrorists {
+			team.Score = analyser.tScore
+		} else {
+			team.Score = analyser.ctScore
+		}
+		if teamWon == p_common.TeamUnassigned || side == teamWon {
+			team.Won = 1
+		}
+
+		if rounds, ok := analyser.teamRounds[analyser.startingSide(side, analyser.roundPlayed)]; ok {
+			team.TRoundsWon, team.CTRoundsWon = rounds.tRoundsWon, rounds.ctRoundsWon
+			team.PistolRoundsWon = rounds.pistolRoundsWon
+			team.EcoRoundsWon = rounds.ecoRoundsWon
+			team.ForceBuyRoundsWon = rounds.forceBuyRoundsWon
+			if rounds.numAreaRounds > 0 {
+				team.AvOccupiedArea = rounds.occupiedArea / float32(rounds.numAreaRounds)
+			}
+		}
+
+		for _, currPlayer := range players {
+			if currPlayer.Team != side {
+				continue
+			}
+			team.Damage += currPlayer.GetTotalDamage()
+			team.FirstKills += currPlayer.GetNumFirstKills()
+			team.ClutchesWon += currPlayer.GetClutchWon()
+			team.Trades += currPlayer.GetNumTrader()
+		}
+		teams = append(teams, team)
+	}
+	sort.SliceStable(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+
+	return teams
+}
+
+// TeamTablePath get path of the team table of a result file
+func TeamTablePath(outPath string) string {
+	return strings.TrimSuffix(outPath, filepath.Ext(outPath)) + TeamTableSuffix
+}
+
+// WriteTeamTable write a row for each team of a match result to given path
+func WriteTeamTable(path string, result *MatchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(teamTableHeader); err != nil {
+		return err
+	}
+	for _, team := range result.Teams {
+		record := []string{
+			team.Name,
+			team.Side,
+			strconv.Itoa(team.Score),
+			strconv.Itoa(team.TRoundsWon),
+			strconv.Itoa(team.CTRoundsWon),
+			strconv.Itoa(team.PistolRoundsWon),
+			strconv.Itoa(team.EcoRoundsWon),
+			strconv.Itoa(team.ForceBuyRoundsWon),
+			strconv.FormatUint(uint64(team.Damage), 10),
+			strconv.FormatUint(uint64(team.FirstKills), 10),
+			strconv.FormatUint(uint64(team.ClutchesWon), 10),
+			strconv.FormatUint(uint64(team.Trades), 10),
+			strconv.FormatFloat(float64(team.AvOccupiedArea), 'f', -1, 32),
+			strconv.Itoa(team.Won),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,58 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+
+	player "github.com/markus-wa/demoinfocs-golang/common"
+	common "github.com/quancore/demoanalyzer-go/common"
+)
+
+// TestBuildTeamResults test that round statistics follow a team after the side swap
+// and teams with equal or empty clan names are kept apart by their side
+func TestBuildTeamResults(t *testing.T) {
+	analyser := &Analyser{
+		rules:       mr15,
+		roundPlayed: 20,
+		tScore:      9,
+		ctScore:     11,
+		teamRounds: map[player.Team]*teamRounds{
+			player.TeamTerrorists:        {tRoundsWon: 8, ctRoundsWon: 3, pistolRoundsWon: 1, firstHalfRoundsWon: 8},
+			player.TeamCounterTerrorists: {tRoundsWon: 2, ctRoundsWon: 7, ecoRoundsWon: 1, firstHalfRoundsWon: 7},
+		},
+	}
+	// team started as terrorist is counter terrorist in the second half
+	ct := common.NewPPlayer(&player.Player{Team: player.TeamCounterTerrorists}, nil)
+	ct.NotifyFirstKill()
+	tr := common.NewPPlayer(&player.Player{Team: player.TeamTerrorists}, nil)
+	tr.NotifyClutchWon()
+	players := []*common.PPlayer{ct, tr}
+
+	startedT := func(name string) TeamResult {
+		return TeamResult{Name: name, Side: "CT", Score: 11, TRoundsWon: 8, CTRoundsWon: 3, PistolRoundsWon: 1,
+			FirstKills: 1, Won: 1}
+	}
+	startedCT := func(name string) TeamResult {
+		return TeamResult{Name: name, Side: "T", Score: 9, TRoundsWon: 2, CTRoundsWon: 7, EcoRoundsWon: 1,
+			ClutchesWon: 1}
+	}
+	cases := []struct {
+		name      string
+		clanNames map[player.Team]string
+		expected  []TeamResult
+	}{
+		{"side swap", map[player.Team]string{player.TeamTerrorists: "B", player.TeamCounterTerrorists: "A"},
+			[]TeamResult{startedT("A"), startedCT("B")}},
+		{"equal clan names", map[player.Team]string{player.TeamTerrorists: "team", player.TeamCounterTerrorists: "team"},
+			[]TeamResult{startedCT("team"), startedT("team")}},
+		{"empty clan names", map[player.Team]string{player.TeamTerrorists: "", player.TeamCounterTerrorists: ""},
+			[]TeamResult{startedCT(""), startedT("")}},
+		{"missing team", map[player.Team]string{player.TeamTerrorists: "B"},
+			[]TeamResult{startedCT("B")}},
+	}
+	for _, c := range cases {
+		if actual := analyser.buildTeamResults(players, c.clanNames); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.expected, actual)
+		}
+	}
+}
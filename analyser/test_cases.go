@@ -1,12 +1,14 @@
 package analyser
 
 import (
+	"fmt"
+
 	"github.com/markus-wa/demoinfocs-golang/common"
 	logging "github.com/sirupsen/logrus"
 )
 
 // testParticipant test participant counts and individual stats
-func (analyser *Analyser) testParticipant() {
+func (analyser *Analyser) testParticipant() error {
 	allplayers := analyser.getAllPlayers()
 	var numActiveT, numActiveCT int
 
@@ -40,29 +42,34 @@ func (analyser *Analyser) testParticipant() {
 		analyser.log.WithFields(logging.Fields{
 			"terrorist number": numActiveT,
 			"team name":        gs.TeamTerrorists().ClanName,
-		}).Fatal("Terrorist team has not enough participant")
+		}).Error("Terrorist team has not enough participant")
+		return newAnalyzeError(ErrNotEnoughParticipants, fmt.Errorf("terrorist team has %d active participants", numActiveT))
 	}
 
 	if numActiveCT < 5 {
 		analyser.log.WithFields(logging.Fields{
 			"ct number": numActiveCT,
 			"team name": gs.TeamCounterTerrorists().ClanName,
-		}).Fatal("CTerrorist team has not enough participant")
+		}).Error("CTerrorist team has not enough participant")
+		return newAnalyzeError(ErrNotEnoughParticipants, fmt.Errorf("counter terrorist team has %d active participants", numActiveCT))
 	}
 
 	analyser.log.Info("All participant test has succesfully passed")
+
+	return nil
 }
 
 // testGameState test game state, played round etc.
-func (analyser *Analyser) testGameState() {
+func (analyser *Analyser) testGameState() error {
 
-	// for a valid match finish, at least 16 round has to played
-	if analyser.roundPlayed < 16 {
+	// for a valid match finish, at least enough rounds to win has to played
+	if analyser.roundPlayed < analyser.rules.minRounds() {
 		analyser.log.WithFields(logging.Fields{
 			"terrorist score":  analyser.tScore,
 			"cterrorist score": analyser.ctScore,
 			"round played":     analyser.roundPlayed,
-		}).Fatal("Played round has wrong")
+		}).Error("Played round has wrong")
+		return newAnalyzeError(ErrInvalidGameState, fmt.Errorf("%d rounds played", analyser.roundPlayed))
 	}
 
 	if !((analyser.tScore + analyser.ctScore) == analyser.roundPlayed) {
@@ -70,32 +77,38 @@ func (analyser *Analyser) testGameState() {
 			"terrorist score":  analyser.tScore,
 			"cterrorist score": analyser.ctScore,
 			"round played":     analyser.roundPlayed,
-		}).Fatal("Played round number is not equal to sum of team scores")
+		}).Error("Played round number is not equal to sum of team scores")
+		return newAnalyzeError(ErrInvalidGameState, fmt.Errorf("%d rounds played, scores %d-%d", analyser.roundPlayed, analyser.tScore, analyser.ctScore))
 	}
 
 	if analyser.tScore < 0 || analyser.ctScore < 0 {
 		analyser.log.WithFields(logging.Fields{
 			"terrorist score":  analyser.tScore,
 			"cterrorist score": analyser.ctScore,
-		}).Fatal("Scores has wrong")
+		}).Error("Scores has wrong")
+		return newAnalyzeError(ErrInvalidGameState, fmt.Errorf("negative score %d-%d", analyser.tScore, analyser.ctScore))
 	}
 
 	// if there is a win it is needed to be at least one team
-	// has reach at least 16
-	if analyser.tScore < 16 && analyser.ctScore < 16 {
+	// has reach rounds to win the match
+	winRounds := analyser.rules.winRounds()
+	if analyser.tScore < winRounds && analyser.ctScore < winRounds && !analyser.rules.isDraw(analyser.tScore, analyser.ctScore) {
 		analyser.log.WithFields(logging.Fields{
 			"terrorist score":  analyser.tScore,
 			"cterrorist score": analyser.ctScore,
-		}).Fatal("Match result has wrong")
+		}).Error("Match result has wrong")
+		return newAnalyzeError(ErrInvalidGameState, fmt.Errorf("no team reached %d rounds, scores %d-%d", winRounds, analyser.tScore, analyser.ctScore))
 	}
 
 	if matchEnded, _ := analyser.checkMatchEnd(analyser.tScore, analyser.ctScore); !matchEnded {
 		analyser.log.WithFields(logging.Fields{
 			"terrorist score":  analyser.tScore,
 			"cterrorist score": analyser.ctScore,
-		}).Fatal("Match is not ended")
+		}).Error("Match is not ended")
+		return newAnalyzeError(ErrMatchNotFinished, fmt.Errorf("scores %d-%d", analyser.tScore, analyser.ctScore))
 	}
 
 	analyser.log.Info("All game state test has succesfully passed")
 
+	return nil
 }
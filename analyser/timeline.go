@@ -0,0 +1,200 @@
+package analyser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	p_common "github.com/markus-wa/demoinfocs-golang/common"
+	"github.com/markus-wa/demoinfocs-golang/events"
+	common "github.com/quancore/demoanalyzer-go/common"
+)
+
+// TimelineSuffix suffix of the timeline written next to a result file
+const TimelineSuffix = "_timeline.ndjson"
+
+// types of timeline events
+const (
+	TimelineRoundStart = "round_start"
+	TimelineRoundEnd   = "round_end"
+	TimelineKill       = "kill"
+	TimelineHurt       = "hurt"
+	TimelineFlash      = "flash"
+	TimelineBombPlant  = "bomb_plant"
+	TimelineBombDefuse = "bomb_defuse"
+	TimelineItemDrop   = "item_drop"
+	TimelineItemPickup = "item_pickup"
+)
+
+// Position position of a player in the map
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// TimelineEvent an event in a valid round of the match
+type TimelineEvent struct {
+	Type  string `json:"type"`
+	Tick  int    `json:"tick"`
+	Round int    `json:"round"`
+	// seconds since the start of the round
+	Time float64 `json:"time"`
+	// player doing the event: killer, attacker, flasher, planter, defuser, dropper or picker
+	ActorID       int64     `json:"actor_id,omitempty"`
+	ActorPosition *Position `json:"actor_position,omitempty"`
+	// player the event is done to: victim, hurt or flashed player
+	TargetID       int64     `json:"target_id,omitempty"`
+	TargetPosition *Position `json:"target_position,omitempty"`
+	AssisterID     int64     `json:"assister_id,omitempty"`
+	Weapon         string    `json:"weapon,omitempty"`
+	Headshot       bool      `json:"headshot,omitempty"`
+	HealthDamage   int       `json:"health_damage,omitempty"`
+	ArmorDamage    int       `json:"armor_damage,omitempty"`
+	// flash duration in seconds
+	FlashDuration float64 `json:"flash_duration,omitempty"`
+	// bomb site, empty if it is not known
+	Site string `json:"site,omitempty"`
+	// side of the round winner
+	Winner string `json:"winner,omitempty"`
+	// scores at the time of the event, scores after the round for a round end
+	TScore  int `json:"t_score"`
+	CTScore int `json:"ct_score"`
+}
+
+// TimelinePath get path of the timeline of a result file
+func TimelinePath(outPath string) string {
+	return strings.TrimSuffix(outPath, filepath.Ext(outPath)) + TimelineSuffix
+}
+
+// WriteTimeline write timeline of a match result to given path as a json object per line
+func WriteTimeline(path string, result *MatchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, ev := range result.Timeline {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newTimelineEvent create a timeline event of the current round
+func (analyser *Analyser) newTimelineEvent(eventType string, tick int) TimelineEvent {
+	return TimelineEvent{
+		Type:    eventType,
+		Tick:    tick,
+		Round:   analyser.currentRound(),
+		Time:    common.TickToSeconds(tick-analyser.roundStart, analyser.tickRate).Seconds(),
+		TScore:  analyser.tScore,
+		CTScore: analyser.ctScore,
+	}
+}
+
+// recordTimeline record a player event in the timeline of the current round
+func (analyser *Analyser) recordTimeline(e interface{}, tick int) {
+	if !analyser.isTimelineEnabled {
+		return
+	}
+
+	var ev TimelineEvent
+	switch e := e.(type) {
+	case events.Kill:
+		ev = analyser.newTimelineEvent(TimelineKill, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Killer)
+		ev.TargetID, ev.TargetPosition = playerIDPosition(e.Victim)
+		ev.AssisterID, _ = playerIDPosition(e.Assister)
+		ev.Weapon = weaponName(e.Weapon)
+		ev.Headshot = e.IsHeadshot
+	case events.PlayerHurt:
+		ev = analyser.newTimelineEvent(TimelineHurt, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Attacker)
+		ev.TargetID, ev.TargetPosition = playerIDPosition(e.Player)
+		ev.Weapon = weaponName(e.Weapon)
+		ev.HealthDamage, ev.ArmorDamage = e.HealthDamage, e.ArmorDamage
+	case events.PlayerFlashed:
+		ev = analyser.newTimelineEvent(TimelineFlash, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Attacker)
+		ev.TargetID, ev.TargetPosition = playerIDPosition(e.Player)
+		ev.FlashDuration = e.FlashDuration().Seconds()
+	case events.BombPlanted:
+		ev = analyser.newTimelineEvent(TimelineBombPlant, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Player)
+		ev.Site = bombsiteName(rune(e.Site))
+	case events.BombDefused:
+		ev = analyser.newTimelineEvent(TimelineBombDefuse, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Player)
+		ev.Site = bombsiteName(rune(e.Site))
+	case events.ItemDrop:
+		ev = analyser.newTimelineEvent(TimelineItemDrop, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Player)
+		ev.Weapon = weaponName(e.Weapon)
+	case events.ItemPickup:
+		ev = analyser.newTimelineEvent(TimelineItemPickup, tick)
+		ev.ActorID, ev.ActorPosition = playerIDPosition(e.Player)
+		ev.Weapon = weaponName(e.Weapon)
+	default:
+		return
+	}
+
+	analyser.roundTimeline = append(analyser.roundTimeline, ev)
+}
+
+// commitRoundTimeline add events of the ended round to the match timeline
+// between its round start and round end events
+func (analyser *Analyser) commitRoundTimeline(tick int) {
+	if !analyser.isTimelineEnabled {
+		return
+	}
+
+	roundStart := analyser.newTimelineEvent(TimelineRoundStart, analyser.roundStart)
+	// scores have already been updated by the round end
+	switch analyser.winnerTeam {
+	case p_common.TeamTerrorists:
+		roundStart.TScore--
+	case p_common.TeamCounterTerrorists:
+		roundStart.CTScore--
+	}
+	roundEnd := analyser.newTimelineEvent(TimelineRoundEnd, tick)
+	roundEnd.Winner = common.GetSideString(analyser.winnerTeam)
+	roundEnd.TScore, roundEnd.CTScore = analyser.tScore, analyser.ctScore
+
+	analyser.timeline = append(analyser.timeline, roundStart)
+	analyser.timeline = append(analyser.timeline, analyser.roundTimeline...)
+	analyser.timeline = append(analyser.timeline, roundEnd)
+	analyser.roundTimeline = nil
+}
+
+// playerIDPosition get steam id and position of a player, zero values if player is nil
+func playerIDPosition(player *p_common.Player) (int64, *Position) {
+	if player == nil {
+		return 0, nil
+	}
+
+	return player.SteamID, &Position{X: player.Position.X, Y: player.Position.Y, Z: player.Position.Z}
+}
+
+// bombsiteName get name of a bomb site, empty if the site is unknown
+func bombsiteName(site rune) string {
+	if site == 0 {
+		return ""
+	}
+
+	return string(site)
+}
+
+// weaponName get name of a weapon, empty if weapon is nil
+func weaponName(weapon *p_common.Equipment) string {
+	if weapon == nil {
+		return ""
+	}
+
+	return weapon.Weapon.String()
+}
@@ -0,0 +1,81 @@
+package analyser
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestWriteTimeline test that each timeline event is written as a json object per line
+// and empty details of an event are omitted while scores are always written
+func TestWriteTimeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := TimelinePath(filepath.Join(dir, "match.json"))
+	if expected := filepath.Join(dir, "match"+TimelineSuffix); path != expected {
+		t.Fatalf("expected timeline path %s, got %s", expected, path)
+	}
+
+	result := &MatchResult{Timeline: []TimelineEvent{
+		{Type: TimelineRoundStart, Tick: 100, Round: 1},
+		{Type: TimelineKill, Tick: 200, Round: 1, Time: 1.5, ActorID: 1, ActorPosition: &Position{X: 1, Y: 2, Z: 3},
+			TargetID: 2, TargetPosition: &Position{X: -1}, Weapon: "AK-47", Headshot: true},
+		{Type: TimelineRoundEnd, Tick: 300, Round: 1, Time: 3, Winner: "T", TScore: 1},
+	}}
+	if err := WriteTimeline(path, result); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []map[string]interface{}
+	var events []TimelineEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d: %v", len(lines)+1, err)
+		}
+		var ev TimelineEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("line %d: %v", len(lines)+1, err)
+		}
+		lines = append(lines, line)
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(events, result.Timeline) {
+		t.Fatalf("expected events %+v, got %+v", result.Timeline, events)
+	}
+	expectedKeys := [][]string{
+		{"type", "tick", "round", "time", "t_score", "ct_score"},
+		{"type", "tick", "round", "time", "actor_id", "actor_position", "target_id", "target_position",
+			"weapon", "headshot", "t_score", "ct_score"},
+		{"type", "tick", "round", "time", "winner", "t_score", "ct_score"},
+	}
+	for i, keys := range expectedKeys {
+		if len(lines[i]) != len(keys) {
+			t.Errorf("line %d: expected keys %v, got %v", i+1, keys, lines[i])
+			continue
+		}
+		for _, key := range keys {
+			if _, ok := lines[i][key]; !ok {
+				t.Errorf("line %d: expected key %s, got %v", i+1, key, lines[i])
+			}
+		}
+	}
+}
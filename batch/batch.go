@@ -0,0 +1,216 @@
+// Package batch package to analyse many demo files concurrently
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	demoio "github.com/quancore/demoanalyzer-go/demoio"
+	"github.com/spf13/viper"
+)
+
+const (
+	logExt = ".log"
+	// name of the database file results of all demos are written into
+	databaseName = "matches"
+)
+
+// Record result of a single demo analyze in a batch
+type Record struct {
+	DemoPath string `json:"demo_path"`
+	// demo name inside an archive, empty for plain or compressed demo files
+	Entry   string `json:"entry"`
+	OutPath string `json:"out_path"`
+	Success bool   `json:"success"`
+	// failure reason, empty on success
+	Error string `json:"error"`
+	// analyze duration in seconds
+	Duration float64 `json:"duration"`
+	MapName  string  `json:"map_name"`
+	TScore   int     `json:"t_score"`
+	CTScore  int     `json:"ct_score"`
+}
+
+// FindDemos walk a directory tree and return all demo files and archives
+func FindDemos(demoDir string) ([]string, error) {
+	var demos []string
+	err := filepath.Walk(demoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// file can be removed while the directory is walked
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() && demoio.IsDemoFile(path) {
+			demos = append(demos, path)
+		}
+		return nil
+	})
+
+	return demos, err
+}
+
+// Run analyse all demo files under demoDir with given number of workers.
+// For each demo a stat file and a log file are written under outDir
+// by keeping relative path of the demo. Demos inside a zip archive are
+// written under a directory named after the archive. Results of a database
+// output format are written into a single database under outDir. Player
+// names are taken from aliases if it is not nil.
+func Run(ctx context.Context, demoDir, outDir string, workers int, aliases map[int64]string) ([]*Record, error) {
+	demos, err := FindDemos(demoDir)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	fileRecords := make([][]*Record, len(demos))
+	var tasks []*Task
+	for i, demoPath := range demos {
+		relPath, err := filepath.Rel(demoDir, demoPath)
+		if err != nil {
+			return nil, err
+		}
+		i, demoPath := i, demoPath
+		basePath := filepath.Join(outDir, trimDemoExt(relPath))
+		tasks = append(tasks, NewTask(func() error {
+			fileRecords[i] = analyseFile(ctx, demoPath, outDir, basePath, aliases)
+			return nil
+		}))
+	}
+
+	p := NewPool(tasks, workers)
+	p.Run()
+
+	var records []*Record
+	for _, currRecords := range fileRecords {
+		records = append(records, currRecords...)
+	}
+
+	return records, nil
+}
+
+// analyseFile analyse all demos in a demo file or archive with given player aliases
+func analyseFile(ctx context.Context, demoPath, outDir, basePath string, aliases map[int64]string) []*Record {
+	format := viper.GetString("output.format")
+	entries, err := demoio.List(demoPath)
+	if err != nil {
+		return []*Record{{DemoPath: demoPath, Error: err.Error()}}
+	} else if len(entries) == 0 {
+		return []*Record{{DemoPath: demoPath, Error: "no demo file found in archive"}}
+	}
+
+	var records []*Record
+	for _, entry := range entries {
+		record := &Record{DemoPath: demoPath}
+		entryBasePath := basePath
+		if entry.Format == demoio.FormatZip {
+			record.Entry = entry.Name
+			entryBasePath = filepath.Join(basePath, trimDemoExt(filepath.FromSlash(entry.Name)))
+		}
+		record.OutPath = entryBasePath + analyser.SinkExt(format)
+		if analyser.IsDatabaseFormat(format) {
+			record.OutPath = filepath.Join(outDir, databaseName+analyser.SinkExt(format))
+		}
+		analyseDemo(ctx, entry, record, entryBasePath, aliases)
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// analyseDemo analyse a demo and fill its record. Log file, round table, team
+// table, match metadata, round ledger and timeline of the demo are written
+// to base path with their own suffixes.
+func analyseDemo(ctx context.Context, entry *demoio.Entry, record *Record, basePath string, aliases map[int64]string) (err error) {
+	logPath := basePath + logExt
+	start := time.Now()
+	defer func() {
+		// a broken demo file can panic inside the parser,
+		// it should only fail the current demo
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		record.Duration = time.Since(start).Seconds()
+		record.Success = err == nil
+		if err != nil {
+			record.Error = err.Error()
+		}
+	}()
+
+	if err = os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(record.OutPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	demoAnalyser, err := analyser.NewAnalyser(f, logPath, viper.GetBool("log.is_method_name"), false)
+	if err != nil {
+		return err
+	}
+	defer demoAnalyser.Close()
+	demoAnalyser.SetAliases(aliases)
+	if viper.GetBool("parse.ledger_cache") {
+		demoAnalyser.SetLedgerPath(analyser.LedgerPath(record.DemoPath))
+	}
+	demoAnalyser.SetOverridePath(analyser.OverridePath(record.DemoPath, record.Entry))
+	result, err := demoAnalyser.Analyze(ctx)
+	if err != nil {
+		return err
+	}
+
+	record.MapName = result.Match.MapAlias
+	record.TScore, record.CTScore = result.Match.TScore, result.Match.CTScore
+
+	if err := analyser.WriteResult(viper.GetString("output.format"), record.OutPath, result); err != nil {
+		return err
+	}
+	if viper.GetBool("output.round_table") {
+		if err := analyser.WriteRoundTable(basePath+analyser.RoundTableSuffix, result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.team_table") {
+		if err := analyser.WriteTeamTable(basePath+analyser.TeamTableSuffix, result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.match_metadata") {
+		if err := analyser.WriteMatchMetadata(basePath+analyser.MatchMetadataSuffix, result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.round_ledger") {
+		if err := analyser.WriteRoundLedger(basePath+analyser.RoundLedgerSuffix, result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.timeline") {
+		return analyser.WriteTimeline(basePath+analyser.TimelineSuffix, result)
+	}
+
+	return nil
+}
+
+// trimDemoExt remove demo and compression extensions from a path
+func trimDemoExt(path string) string {
+	for demoio.IsDemoFile(path) {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+
+	return path
+}
@@ -0,0 +1,56 @@
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manifest csv header
+var manifestHeader = []string{"demo_path", "entry", "out_path", "success", "error", "duration", "map_name", "t_score", "ct_score"}
+
+// WriteManifest write batch records to given path.
+// If the extension of path is .json, records are written as a json array, otherwise as csv.
+func WriteManifest(path string, records []*Record) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	w := csv.NewWriter(f)
+	if err := w.Write(manifestHeader); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := []string{
+			record.DemoPath,
+			record.Entry,
+			record.OutPath,
+			fmt.Sprint(record.Success),
+			record.Error,
+			fmt.Sprintf("%.3f", record.Duration),
+			record.MapName,
+			fmt.Sprint(record.TScore),
+			fmt.Sprint(record.CTScore),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
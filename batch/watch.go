@@ -0,0 +1,200 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Watcher analyse demo files as they land in a directory. A demo is analysed
+// after its size and modification time have not changed for SettleTime, and
+// it is recorded in a state file so that it is not analysed again after a restart.
+type Watcher struct {
+	DemoDir string
+	// outputs are written under OutDir by keeping relative path of the demo
+	OutDir  string
+	Workers int
+	// canonical names of players by their SteamID64
+	Aliases map[int64]string
+	// period of scanning the demo directory
+	PollInterval time.Duration
+	// duration a demo file has to stay unchanged to be analysed
+	SettleTime time.Duration
+	// path of the state file recording processed demos
+	StatePath string
+	// optional function called after a demo file has been processed
+	Notify func(demoPath string, records []*Record)
+
+	state   *watchState
+	pending map[string]*pendingDemo
+}
+
+// watchState processed demos by their path relative to demo directory
+type watchState struct {
+	Processed map[string]*processedDemo `json:"processed"`
+}
+
+// processedDemo a demo file analysed by the watcher
+type processedDemo struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Records []*Record `json:"records"`
+}
+
+// pendingDemo a demo file waiting to stop growing
+type pendingDemo struct {
+	size    int64
+	modTime time.Time
+	// first time the current size has been seen
+	since time.Time
+}
+
+// Run watch demo directory until the context is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	state, err := readWatchState(w.StatePath)
+	if err != nil {
+		return err
+	}
+	w.state = state
+	w.pending = make(map[string]*pendingDemo)
+
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.scan(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan find new demo files and analyse the ones stopped growing
+func (w *Watcher) scan(ctx context.Context) error {
+	demos, err := FindDemos(w.DemoDir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var ready []string
+	for _, demoPath := range demos {
+		info, err := os.Stat(demoPath)
+		if err != nil {
+			// file can be removed after the scan
+			continue
+		}
+		relPath, err := filepath.Rel(w.DemoDir, demoPath)
+		if err != nil {
+			return err
+		}
+		if processed, ok := w.state.Processed[relPath]; ok &&
+			processed.Size == info.Size() && processed.ModTime.Equal(info.ModTime()) {
+			continue
+		}
+		seen[demoPath] = true
+
+		pending, ok := w.pending[demoPath]
+		if !ok || pending.size != info.Size() || !pending.modTime.Equal(info.ModTime()) {
+			w.pending[demoPath] = &pendingDemo{size: info.Size(), modTime: info.ModTime(), since: now}
+			continue
+		}
+		if now.Sub(pending.since) >= w.SettleTime {
+			ready = append(ready, demoPath)
+		}
+	}
+	// forget removed files
+	for demoPath := range w.pending {
+		if !seen[demoPath] {
+			delete(w.pending, demoPath)
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	return w.analyse(ctx, ready)
+}
+
+// analyse analyse demo files and record them as processed
+func (w *Watcher) analyse(ctx context.Context, demos []string) error {
+	fileRecords := make([][]*Record, len(demos))
+	var tasks []*Task
+	for i, demoPath := range demos {
+		relPath, err := filepath.Rel(w.DemoDir, demoPath)
+		if err != nil {
+			return err
+		}
+		i, demoPath := i, demoPath
+		basePath := filepath.Join(w.OutDir, trimDemoExt(relPath))
+		tasks = append(tasks, NewTask(func() error {
+			fileRecords[i] = analyseFile(ctx, demoPath, w.OutDir, basePath, w.Aliases)
+			return nil
+		}))
+	}
+	workers := w.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	NewPool(tasks, workers).Run()
+
+	// an analyze stopped by cancellation is not a processed demo
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	for i, demoPath := range demos {
+		pending := w.pending[demoPath]
+		relPath, _ := filepath.Rel(w.DemoDir, demoPath)
+		w.state.Processed[relPath] = &processedDemo{Size: pending.size, ModTime: pending.modTime, Records: fileRecords[i]}
+		delete(w.pending, demoPath)
+		if w.Notify != nil {
+			w.Notify(demoPath, fileRecords[i])
+		}
+	}
+
+	return writeWatchState(w.StatePath, w.state)
+}
+
+// readWatchState read watch state file, a missing file is an empty state
+func readWatchState(path string) (*watchState, error) {
+	state := &watchState{Processed: make(map[string]*processedDemo)}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, err
+	}
+	if state.Processed == nil {
+		state.Processed = make(map[string]*processedDemo)
+	}
+
+	return state, nil
+}
+
+// writeWatchState write watch state file by replacing it at once
+func writeWatchState(path string, state *watchState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatchPending test that growing and processed demos are not analysed
+func TestWatchPending(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	demoPath := filepath.Join(dir, "match.dem")
+	if err := ioutil.WriteFile(demoPath, []byte("HL2DEMO\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(demoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statePath := filepath.Join(dir, "out", "watch_state.json")
+	state := &watchState{Processed: map[string]*processedDemo{
+		"match.dem": {Size: info.Size(), ModTime: info.ModTime()},
+	}}
+	if err := writeWatchState(statePath, state); err != nil {
+		t.Fatal(err)
+	}
+	readState, err := readWatchState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{DemoDir: dir, SettleTime: time.Hour, state: readState, pending: make(map[string]*pendingDemo)}
+	if err := w.scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pending) != 0 {
+		t.Fatalf("processed demo is pending")
+	}
+
+	// a demo being written waits until it stops growing
+	newPath := filepath.Join(dir, "new.dem")
+	if err := ioutil.WriteFile(newPath, []byte("HL2DEMO\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.scan(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := w.pending[newPath]; !ok {
+		t.Fatalf("new demo is not pending")
+	}
+	if _, ok := w.state.Processed["new.dem"]; ok {
+		t.Fatalf("new demo is processed before settling")
+	}
+}
+
+// TestWatchRemoved test that a demo removed between scans is forgotten without an error
+func TestWatchRemoved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	demoPath := filepath.Join(dir, "sub", "match.dem")
+	if err := os.MkdirAll(filepath.Dir(demoPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(demoPath, []byte("HL2DEMO\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{DemoDir: dir, SettleTime: time.Hour, state: &watchState{Processed: make(map[string]*processedDemo)},
+		pending: make(map[string]*pendingDemo)}
+	if err := w.scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.pending[demoPath]; !ok {
+		t.Fatalf("demo is not pending")
+	}
+
+	if err := os.RemoveAll(filepath.Dir(demoPath)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.scan(context.Background()); err != nil {
+		t.Fatalf("scan after removal: %v", err)
+	}
+	if len(w.pending) != 0 {
+		t.Fatalf("removed demo is pending")
+	}
+}
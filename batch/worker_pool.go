@@ -1,4 +1,4 @@
-package testAnalyser
+package batch
 
 import (
 	"sync"
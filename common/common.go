@@ -25,6 +25,22 @@ const (
 	ForceBuyRound RoundType = 4
 )
 
+// String return name of the round type
+func (t RoundType) String() string {
+	switch t {
+	case NormalRound:
+		return "normal"
+	case PistolRound:
+		return "pistol"
+	case EcoRound:
+		return "eco"
+	case ForceBuyRound:
+		return "force_buy"
+	}
+
+	return "unknown"
+}
+
 // #################################
 
 // ######## Common structs #########
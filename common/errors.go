@@ -0,0 +1,12 @@
+package common
+
+import "errors"
+
+// ########## errors ################
+
+var (
+	// ErrNavMeshMissing nav mesh file of the map can not be found or parsed
+	ErrNavMeshMissing = errors.New("nav mesh is missing")
+	// ErrUnknownRoundType round type is not one of the known round types
+	ErrUnknownRoundType = errors.New("unknown round type")
+)
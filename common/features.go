@@ -0,0 +1,291 @@
+package common
+
+import (
+	"github.com/quancore/demoanalyzer-go/utils"
+)
+
+// Normalization way of normalising raw value of a feature
+type Normalization int
+
+// normalisations of a feature
+const (
+	// NormNone raw value is already normalised
+	NormNone Normalization = iota
+	// NormPerRound raw value divided by the number of rounds the player participated
+	NormPerRound
+	// NormPerKill raw value divided by the number of kills of the player
+	NormPerKill
+	// NormPerShot raw value divided by the number of shots of the player
+	NormPerShot
+	// NormRatio raw value divided by the denominator of the feature
+	NormRatio
+)
+
+// String return name of the normalisation
+func (n Normalization) String() string {
+	switch n {
+	case NormPerRound:
+		return "per_round"
+	case NormPerKill:
+		return "per_kill"
+	case NormPerShot:
+		return "per_shot"
+	case NormRatio:
+		return "ratio"
+	}
+
+	return "none"
+}
+
+// Feature a player feature written to outputs
+type Feature struct {
+	// column name of the feature
+	Name        string
+	Description string
+	// raw accumulated value of the player
+	Raw func(p *PPlayer) float32
+	// normalisation applied to raw value
+	Norm Normalization
+	// raw value is an integer count
+	Count bool
+	// name and value of the denominator of a ratio feature
+	DenominatorName string
+	Denominator     func(p *PPlayer) float32
+}
+
+// suffix of raw value columns in raw count mode
+const rawSuffix = "_Raw"
+
+// names of denominators of normalisations
+const (
+	roundsDenominator      = "Rounds_Played"
+	matchRoundsDenominator = "Match_Rounds_Played"
+	killsDenominator       = "Kills"
+	shotsDenominator       = "Shots"
+)
+
+// denominator name of the feature, empty if it is not normalised
+func (f *Feature) denominatorName() string {
+	switch f.Norm {
+	case NormPerRound:
+		return roundsDenominator
+	case NormPerKill:
+		return killsDenominator
+	case NormPerShot:
+		return shotsDenominator
+	case NormRatio:
+		return f.DenominatorName
+	}
+
+	return ""
+}
+
+// denominator value of the feature
+func (f *Feature) denominator(p *PPlayer) float32 {
+	switch f.Norm {
+	case NormPerRound:
+		return float32(p.GetRoundsParticipated())
+	case NormPerKill:
+		return float32(p.kill)
+	case NormPerShot:
+		return float32(p.shots)
+	case NormRatio:
+		return f.Denominator(p)
+	}
+
+	return 0
+}
+
+// Value return normalised value of the feature for a player
+func (f *Feature) Value(p *PPlayer) float32 {
+	if f.Norm == NormNone {
+		return f.Raw(p)
+	}
+
+	return utils.SafeDivision(f.Raw(p), f.denominator(p))
+}
+
+// Features registry of player features in the order of output columns.
+// To add a new feature, append it here; header and values of all outputs are
+// generated from this list.
+var Features = []*Feature{
+	{Name: "Pistol_Rounds_Won_Percentage", Description: "ratio of pistol rounds won",
+		Raw: func(p *PPlayer) float32 { return float32(p.pistolRoundWon) }, Norm: NormRatio,
+		DenominatorName: "Pistol_Rounds", Denominator: func(p *PPlayer) float32 { return float32(p.pistolRoundWon + p.pistolRoundslost) }, Count: true},
+	{Name: "HS_Percentage", Description: "ratio of kills by headshot",
+		Raw: func(p *PPlayer) float32 { return float32(p.hsKill) }, Norm: NormPerKill, Count: true},
+	{Name: "Clutches_Won", Description: "clutches won per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.clutchesWon) }, Norm: NormPerRound, Count: true},
+	{Name: "ADR", Description: "average damage per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.totalDmg) }, Norm: NormPerRound, Count: true},
+	{Name: "FPR", Description: "kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.kill) }, Norm: NormPerRound, Count: true},
+	{Name: "FKR", Description: "first kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.firstKill) }, Norm: NormPerRound, Count: true},
+	{Name: "APR", Description: "assists per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.assist) }, Norm: NormPerRound, Count: true},
+	{Name: "K_D_Diff_Round", Description: "kill death difference per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.kill) - float32(p.death) }, Norm: NormPerRound, Count: true},
+	{Name: "Flash_Assists_Round", Description: "flash assists per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.flashAssists) }, Norm: NormPerRound, Count: true},
+	{Name: "Blind_Players_Killed_Round", Description: "blinded opponents killed per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.blindPlayersKilled) }, Norm: NormPerRound, Count: true},
+	{Name: "Blind_Kills_Round", Description: "kills while blinded per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.blindKills) }, Norm: NormPerRound, Count: true},
+	{Name: "Grenade_Damage_Round", Description: "grenade damage per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.heDmg) }, Norm: NormPerRound, Count: true},
+	{Name: "Fire_Damage_Round", Description: "fire damage per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.fireDmg) }, Norm: NormPerRound, Count: true},
+	{Name: "Time_Flashing_Opponents_Round", Description: "seconds of opponents blinded per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.timeFlashingOpponents.Seconds()) }, Norm: NormPerRound},
+	{Name: "Accuracy", Description: "ratio of shots hit",
+		Raw: func(p *PPlayer) float32 { return float32(p.shotsHit) }, Norm: NormPerShot, Count: true},
+	{Name: "Num_Times_Trader", Description: "trade kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numTrader) }, Norm: NormPerRound, Count: true},
+	{Name: "Num_Times_Tradee", Description: "deaths traded by a teammate per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numTradee) }, Norm: NormPerRound, Count: true},
+	{Name: "KAST", Description: "rounds with a kill, assist, survival or trade per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.kast) }, Norm: NormPerRound, Count: true},
+	{Name: "MVP", Description: "mvps per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numMVP) }, Norm: NormPerRound, Count: true},
+	{Name: "Money_Saved_Round", Description: "money saved per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.totalSavedMoney) }, Norm: NormPerRound, Count: true},
+	{Name: "Sniper_Kill_Round", Description: "sniper rifle kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillSniperRifle) }, Norm: NormPerRound, Count: true},
+	{Name: "Melee_Kill_Round", Description: "melee kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillMelee) }, Norm: NormPerRound, Count: true},
+	{Name: "Shotgun_Kill_Round", Description: "shotgun kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillShotgun) }, Norm: NormPerRound, Count: true},
+	{Name: "AssultR_Kill_Round", Description: "assault rifle kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillAssultRifle) }, Norm: NormPerRound, Count: true},
+	{Name: "Pistol_Kill_Round", Description: "pistol kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillPistol) }, Norm: NormPerRound, Count: true},
+	{Name: "MachineGun_Kill_Round", Description: "machine gun kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillMachineGun) }, Norm: NormPerRound, Count: true},
+	{Name: "SMG_Kill_Round", Description: "smg kills per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.numKillSMG) }, Norm: NormPerRound, Count: true},
+	{Name: "Head_Hit", Description: "ratio of hits to head",
+		Raw: func(p *PPlayer) float32 { return float32(p.numHitHead) }, Norm: NormRatio, DenominatorName: "Shots_Hit", Denominator: shotsHit, Count: true},
+	{Name: "Stomach_Hit", Description: "ratio of hits to stomach",
+		Raw: func(p *PPlayer) float32 { return float32(p.numHitStomach) }, Norm: NormRatio, DenominatorName: "Shots_Hit", Denominator: shotsHit, Count: true},
+	{Name: "Chest_Hit", Description: "ratio of hits to chest",
+		Raw: func(p *PPlayer) float32 { return float32(p.numHitChest) }, Norm: NormRatio, DenominatorName: "Shots_Hit", Denominator: shotsHit, Count: true},
+	{Name: "Legs_Hit", Description: "ratio of hits to legs",
+		Raw: func(p *PPlayer) float32 { return float32(p.numHitLegs) }, Norm: NormRatio, DenominatorName: "Shots_Hit", Denominator: shotsHit, Count: true},
+	{Name: "Arms_Hit", Description: "ratio of hits to arms",
+		Raw: func(p *PPlayer) float32 { return float32(p.numHitArms) }, Norm: NormRatio, DenominatorName: "Shots_Hit", Denominator: shotsHit, Count: true},
+	{Name: "Unit_Damage_Cost", Description: "equipment cost per damage given",
+		Raw: func(p *PPlayer) float32 { return p.damageCost }, Norm: NormRatio,
+		DenominatorName: "Damage", Denominator: func(p *PPlayer) float32 { return float32(p.totalDmg) }},
+	{Name: "Av_Kill_Distance", Description: "average distance to killed opponents",
+		Raw: func(p *PPlayer) float32 { return p.totalKillDistance }, Norm: NormPerKill},
+	{Name: "Player_Saved_Round", Description: "teammates saved per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.savedFriends) }, Norm: NormPerRound, Count: true},
+	{Name: "Player_Won_Health_Round", Description: "remaining health after won rounds per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.totalHealthWon) }, Norm: NormPerRound, Count: true},
+	{Name: "Player_Lost_Health_Round", Description: "remaining health after lost rounds per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.totalHealthLost) }, Norm: NormPerRound, Count: true},
+	{Name: "Last_Member_Survived_Round", Description: "rounds survived as the last member per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.lastMemberSurvived) }, Norm: NormPerRound, Count: true},
+	{Name: "Time_Hurt_To_Kill", Description: "average seconds from first damage to kill",
+		Raw: func(p *PPlayer) float32 { return float32(p.timeHurtToKill.Seconds()) }, Norm: NormPerKill},
+	{Name: "Spray_Sniper", Description: "sniper rifle spray per kill",
+		Raw: func(p *PPlayer) float32 { return p.spraySniperRifle }, Norm: NormPerKill},
+	{Name: "Spray_Shotgun", Description: "shotgun spray per kill",
+		Raw: func(p *PPlayer) float32 { return p.sprayShotgun }, Norm: NormPerKill},
+	{Name: "Spray_ARifle", Description: "assault rifle spray per kill",
+		Raw: func(p *PPlayer) float32 { return p.sprayAssultRifle }, Norm: NormPerKill},
+	{Name: "Spray_Pistol", Description: "pistol spray per kill",
+		Raw: func(p *PPlayer) float32 { return p.sprayPistol }, Norm: NormPerKill},
+	{Name: "Spray_Machinegun", Description: "machine gun spray per kill",
+		Raw: func(p *PPlayer) float32 { return p.sprayMachineGun }, Norm: NormPerKill},
+	{Name: "Spray_SMG", Description: "smg spray per kill",
+		Raw: func(p *PPlayer) float32 { return p.spraySMG }, Norm: NormPerKill},
+	{Name: "Round_Win_Percentage", Description: "ratio of rounds won",
+		Raw: func(p *PPlayer) float32 { return p.roundWinPercentage }, Norm: NormNone},
+	{Name: "Round_Wintime", Description: "seconds of won rounds per round",
+		Raw: func(p *PPlayer) float32 { return float32(p.totalRoundWinTime.Seconds()) }, Norm: NormPerRound},
+	{Name: "Duck_Kill", Description: "ratio of kills while ducking",
+		Raw: func(p *PPlayer) float32 { return float32(p.duckKill) }, Norm: NormPerKill, Count: true},
+	{Name: "Member_Death_Distance_Round", Description: "distance to killed teammates per round",
+		Raw: func(p *PPlayer) float32 { return p.totalMemberKilledDistance }, Norm: NormPerRound},
+	{Name: "Sniper_Killed", Description: "ratio of kills on opponents holding a sniper rifle",
+		Raw: func(p *PPlayer) float32 { return float32(p.sniperKilled) }, Norm: NormPerKill, Count: true},
+	{Name: "Occupied_Area_Round", Description: "map area controlled by the team per round",
+		Raw: func(p *PPlayer) float32 { return p.teamOccupiedArea }, Norm: NormPerRound},
+}
+
+// shotsHit denominator of hit group features
+func shotsHit(p *PPlayer) float32 { return float32(p.shotsHit) }
+
+// FeatureNames return names of registered features in output order
+func FeatureNames() []string {
+	names := make([]string, len(Features))
+	for i, feature := range Features {
+		names[i] = feature.Name
+	}
+
+	return names
+}
+
+// rawDenominators features having a distinct denominator in the order of their first appearance
+func rawDenominators() []*Feature {
+	var features []*Feature
+	seen := make(map[string]bool)
+	for _, feature := range Features {
+		name := feature.denominatorName()
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		features = append(features, feature)
+	}
+
+	return features
+}
+
+// RawFeatureNames return names of raw values of registered features followed
+// by names of their denominators, used in raw count mode
+func RawFeatureNames() []string {
+	var names []string
+	for _, feature := range Features {
+		names = append(names, feature.Name+rawSuffix)
+	}
+	for _, feature := range rawDenominators() {
+		names = append(names, feature.denominatorName())
+	}
+	names = append(names, matchRoundsDenominator)
+
+	return names
+}
+
+// CountColumns return names of raw count mode columns holding integer counts.
+// Denominators of all features are counts.
+func CountColumns() map[string]bool {
+	columns := map[string]bool{matchRoundsDenominator: true}
+	for _, feature := range Features {
+		if feature.Count {
+			columns[feature.Name+rawSuffix] = true
+		}
+	}
+	for _, feature := range rawDenominators() {
+		columns[feature.denominatorName()] = true
+	}
+
+	return columns
+}
+
+// RawFeatureValues return raw values of registered features of the player followed
+// by their denominators in the order of RawFeatureNames
+func (p *PPlayer) RawFeatureValues(roundPlayed int) []float32 {
+	var values []float32
+	for _, feature := range Features {
+		values = append(values, feature.Raw(p))
+	}
+	for _, feature := range rawDenominators() {
+		values = append(values, feature.denominator(p))
+	}
+	values = append(values, float32(roundPlayed))
+
+	return values
+}
@@ -0,0 +1,77 @@
+package common
+
+import "testing"
+
+// TestFeatureColumns test that feature names are unique and match the number of values
+func TestFeatureColumns(t *testing.T) {
+	p := NewPPlayer(nil, nil)
+	p.kill, p.shots, p.shotsHit, p.numHitHead = 4, 10, 5, 2
+	p.SetRoundsParticipated(2)
+
+	cases := []struct {
+		names  []string
+		values []float32
+	}{
+		{FeatureNames(), p.FeatureValues()},
+		{RawFeatureNames(), p.RawFeatureValues(2)},
+	}
+	for _, c := range cases {
+		if len(c.names) != len(c.values) {
+			t.Fatalf("%d names for %d values", len(c.names), len(c.values))
+		}
+		seen := make(map[string]bool)
+		for _, name := range c.names {
+			if seen[name] {
+				t.Errorf("duplicate feature name %s", name)
+			}
+			seen[name] = true
+		}
+	}
+
+	values := make(map[string]float32)
+	for i, name := range FeatureNames() {
+		values[name] = p.FeatureValues()[i]
+	}
+	if values["FPR"] != 2 || values["Accuracy"] != 0.5 || values["Head_Hit"] != 0.4 {
+		t.Errorf("unexpected normalised values %v", values)
+	}
+
+	// per round features are normalised by rounds participated
+	p.SetRoundsParticipated(0)
+	p.NotifyRoundParticipated()
+	for i, name := range FeatureNames() {
+		values[name] = p.FeatureValues()[i]
+	}
+	if values["FPR"] != 4 {
+		t.Errorf("expected FPR 4 for a single participated round, got %v", values["FPR"])
+	}
+	rawValues := p.RawFeatureValues(2)
+	if rawValues[len(rawValues)-1] != 2 {
+		t.Errorf("expected match-wide rounds played 2, got %v", rawValues[len(rawValues)-1])
+	}
+
+	// a player who has not participated any round is not normalised by match-wide rounds
+	p.SetRoundsParticipated(0)
+	if fpr := p.FeatureValues()[0]; fpr != 0 {
+		t.Errorf("expected FPR 0 without participated rounds, got %v", fpr)
+	}
+}
+
+// TestCountColumns test that count columns are raw count mode columns holding integer counts
+func TestCountColumns(t *testing.T) {
+	rawNames := make(map[string]bool)
+	for _, name := range RawFeatureNames() {
+		rawNames[name] = true
+	}
+	counts := CountColumns()
+	for name := range counts {
+		if !rawNames[name] {
+			t.Errorf("count column %s is not a raw column", name)
+		}
+	}
+	for name, expected := range map[string]bool{"FPR_Raw": true, "Rounds_Played": true, "Av_Kill_Distance_Raw": false, "FPR": false} {
+		if counts[name] != expected {
+			t.Errorf("%s: expected count %v", name, expected)
+		}
+	}
+}
@@ -172,12 +172,13 @@ func (navigator *Navigator) Parse(mapName string) error {
 		navigator.log.WithFields(logging.Fields{
 			"error": readErr.Error(),
 		}).Error("File failed to read ")
-		return readErr
+		return fmt.Errorf("%w: %v", ErrNavMeshMissing, readErr)
 	}
+	defer f.Close()
 	parser := gonav.Parser{Reader: f}
 	navigator.mesh, err = parser.Parse() // Parse the file
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", ErrNavMeshMissing, err)
 	}
 	navigator.log.Info("Mesh has succesfully parsed")
 
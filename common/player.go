@@ -9,7 +9,6 @@ import (
 	"github.com/golang/geo/r3"
 	player "github.com/markus-wa/demoinfocs-golang/common"
 	event "github.com/markus-wa/demoinfocs-golang/events"
-	"github.com/quancore/demoanalyzer-go/utils"
 	log "github.com/sirupsen/logrus"
 	viper "github.com/spf13/viper"
 )
@@ -18,6 +17,35 @@ const (
 	specifier = ","
 )
 
+// viewDirection yaw and pitch value of a player at a tick
+type viewDirection struct {
+	tick int
+	x    float32
+	y    float32
+}
+
+// RoundStats raw statistics of a player in a single round
+type RoundStats struct {
+	Kills        uint
+	Deaths       uint
+	Assists      uint
+	Damage       uint
+	FirstKill    bool
+	FlashAssists uint
+	MoneySaved   int
+}
+
+// roundCounters counters of a player used to find statistics of a round
+type roundCounters struct {
+	kill         uint
+	death        uint
+	assist       uint
+	totalDmg     uint
+	firstKill    uint
+	flashAssists uint
+	savedMoney   int
+}
+
 // PPlayer is an abstraction struct on top of parser player struct.
 // It includes all player level info in its struct for a player.
 type PPlayer struct {
@@ -63,7 +91,7 @@ type PPlayer struct {
 	lastFootstepTick int
 	// The round variable that number of killed team members
 	numKilledMembers int
-	// The number of rounds played by this player
+	// The number of rounds this player was alive at freeze time end
 	numRoundsPlayed uint
 	// Number of damage firstly given. Used as normalization on POV to damage
 	numFirstDamage int
@@ -164,6 +192,12 @@ type PPlayer struct {
 	// the last yaw and pitch value of the player
 	lastViewDirectionX float32
 	lastViewDirectionY float32
+	// recent yaw and pitch values of the player, used in single pass mode
+	viewHistory []viewDirection
+	// player state at the start of the staged round, nil if no round staged
+	roundSnapshot *PPlayer
+	// counters at the end of the last recorded round
+	lastRoundCounters roundCounters
 
 	// ******* weapon stats ******
 	numKillMelee       int
@@ -408,6 +442,13 @@ func (p *PPlayer) GetShotsHit() uint { return p.shotsHit }
 // GetNumFirstDamage get the number of first damage given
 func (p *PPlayer) GetNumFirstDamage() int { return p.numFirstDamage }
 
+// GetRoundsParticipated get number of rounds participated
+func (p *PPlayer) GetRoundsParticipated() int { return int(p.numRoundsPlayed) }
+
+// SetRoundsParticipated set number of rounds participated, used for a demo
+// without freeze time end events where participation can not be counted
+func (p *PPlayer) SetRoundsParticipated(roundsPlayed int) { p.numRoundsPlayed = uint(roundsPlayed) }
+
 // GetKAST get kast have done
 func (p *PPlayer) GetKAST() uint { return p.kast }
 
@@ -497,6 +538,32 @@ func (p *PPlayer) SetLastYawPitch() (float32, float32) {
 	return p.lastViewDirectionX, p.lastViewDirectionY
 }
 
+// SetLastYawPitchAt record player yaw and pitch value at given past tick
+// by using recorded view directions
+func (p *PPlayer) SetLastYawPitchAt(tick int) (float32, float32) {
+	p.lastViewDirectionX = p.ViewDirectionX
+	p.lastViewDirectionY = p.ViewDirectionY
+	for i := len(p.viewHistory) - 1; i >= 0; i-- {
+		if p.viewHistory[i].tick <= tick {
+			p.lastViewDirectionX = p.viewHistory[i].x
+			p.lastViewDirectionY = p.viewHistory[i].y
+			break
+		}
+	}
+	return p.lastViewDirectionX, p.lastViewDirectionY
+}
+
+// RecordViewDirection record current yaw and pitch value of the player
+// and drop the records older than keepTicks
+func (p *PPlayer) RecordViewDirection(tick, keepTicks int) {
+	p.viewHistory = append(p.viewHistory, viewDirection{tick: tick, x: p.ViewDirectionX, y: p.ViewDirectionY})
+	oldest := 0
+	for oldest < len(p.viewHistory)-1 && p.viewHistory[oldest].tick < tick-keepTicks {
+		oldest++
+	}
+	p.viewHistory = p.viewHistory[oldest:]
+}
+
 // SetLastFlashedBy set the id of the player who flashed this player
 func (p *PPlayer) SetLastFlashedBy(attackerID int64, lastvalidTick int64) {
 	p.lastFlashedBy = attackerID
@@ -831,6 +898,9 @@ func (p *PPlayer) notifyWeaponType(weapon *player.Equipment) {
 	}
 }
 
+// NotifyRoundParticipated handle event of being alive at freeze time end of a round
+func (p *PPlayer) NotifyRoundParticipated() { p.numRoundsPlayed++ }
+
 // NotifyRoundStart handle event of round start
 func (p *PPlayer) NotifyRoundStart() {
 	p.lastFlashedBy = 0
@@ -845,7 +915,7 @@ func (p *PPlayer) NotifyRoundStart() {
 }
 
 // NotifySpecialRoundWon handle event of won a special round
-func (p *PPlayer) NotifySpecialRoundWon(RoundType RoundType) {
+func (p *PPlayer) NotifySpecialRoundWon(RoundType RoundType) error {
 	switch RoundType {
 	case PistolRound:
 		p.pistolRoundWon++
@@ -856,12 +926,14 @@ func (p *PPlayer) NotifySpecialRoundWon(RoundType RoundType) {
 	case NormalRound:
 
 	default:
-		log.Fatal("Unexpected type of round")
+		return fmt.Errorf("%w: %d", ErrUnknownRoundType, RoundType)
 	}
+
+	return nil
 }
 
 // NotifySpecialRoundLost handle event of lost a special round
-func (p *PPlayer) NotifySpecialRoundLost(RoundType RoundType) {
+func (p *PPlayer) NotifySpecialRoundLost(RoundType RoundType) error {
 	switch RoundType {
 	case PistolRound:
 		p.pistolRoundslost++
@@ -872,8 +944,10 @@ func (p *PPlayer) NotifySpecialRoundLost(RoundType RoundType) {
 	case NormalRound:
 
 	default:
-		log.Fatal("Unexpected type of round")
+		return fmt.Errorf("%w: %d", ErrUnknownRoundType, RoundType)
 	}
+
+	return nil
 }
 
 // NotifyClutchWon handle event of updating clutch
@@ -1060,185 +1134,92 @@ func (p *PPlayer) ResetPlayerState() {
 	p.numHitArms = 0
 	p.numHitLegs = 0
 	p.numHitStomach = 0
+	p.lastRoundCounters = roundCounters{}
 	// maps
 	p.lastHurt = make(map[int64]*HurtTuples)
 	p.spottedPlayers = make(map[int64]*SpottedPlayer)
 
 }
 
-// OutputPlayerState output as string form of current player state
-func (p *PPlayer) OutputPlayerState(sb strings.Builder, roundPlayed, Won, tScore, ctScore int) strings.Builder {
-	roundPlayedf := float32(roundPlayed)
-	// score := p.GetPlayerScore(tScore, ctScore)
-
-	playerName := p.Name
-	playerName = strings.Replace(playerName, specifier, " ", -1)
-	sb.WriteString(fmt.Sprintf("%s%s", playerName, specifier))
-
-	// sb.WriteString(fmt.Sprintf("%s%s", fmt.Sprint(score), specifier))
-
-	var pistolRoundWonPercentage float32
-	pistolROundsWon := float32(p.pistolRoundWon)
-	pistolROundsLost := float32(p.pistolRoundslost)
-	if (pistolROundsWon + pistolROundsLost) > 0 {
-		pistolRoundWonPercentage = pistolROundsWon / (pistolROundsWon + pistolROundsLost)
+// FeatureValues return normalised feature values of the player in the order of
+// registered features
+func (p *PPlayer) FeatureValues() []float32 {
+	values := make([]float32, len(Features))
+	for i, feature := range Features {
+		values[i] = feature.Value(p)
 	}
-	sb.WriteString(fmt.Sprintf("%s%s", fmt.Sprintf("%.3f", pistolRoundWonPercentage), specifier))
-
-	sb.WriteString(fmt.Sprintf("%s%s", fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.hsKill), float32(p.kill))), specifier))
-
-	clutchesWon := fmt.Sprintf("%.3f", float32(p.clutchesWon)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", clutchesWon, specifier))
-	// sb.WriteString(fmt.Sprintf("%s%s", fmt.Sprint(currPlayer.GetClutchWon()), specifier))
-
-	adr := fmt.Sprintf("%.3f", float32(p.totalDmg)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", adr, specifier))
-
-	fpr := fmt.Sprintf("%.3f", float32(p.kill)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", fpr, specifier))
-
-	fkr := fmt.Sprintf("%.3f", float32(p.firstKill)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", fkr, specifier))
-
-	apr := fmt.Sprintf("%.3f", float32(p.assist)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", apr, specifier))
-
-	kdDiff := fmt.Sprintf("%.3f", (float32(p.kill)-float32(p.death))/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", kdDiff, specifier))
-
-	flashAssist := fmt.Sprintf("%.3f", float32(p.flashAssists)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", flashAssist, specifier))
-
-	blindPlayerKilled := fmt.Sprintf("%.3f", float32(p.blindPlayersKilled)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", blindPlayerKilled, specifier))
-
-	blindKills := fmt.Sprintf("%.3f", float32(p.blindKills)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", blindKills, specifier))
-
-	granedaDamage := fmt.Sprintf("%.3f", float32(p.heDmg)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", granedaDamage, specifier))
-
-	fireDamage := fmt.Sprintf("%.3f", float32(p.fireDmg)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", fireDamage, specifier))
-
-	timeFlashingOpponent := fmt.Sprintf("%.3f", float32(p.timeFlashingOpponents.Seconds())/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", timeFlashingOpponent, specifier))
-
-	accuracyStr := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.shotsHit), float32(p.shots)))
-	sb.WriteString(fmt.Sprintf("%s%s", accuracyStr, specifier))
-
-	numTrader := fmt.Sprintf("%.3f", float32(p.numTrader)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", numTrader, specifier))
-
-	numTradee := fmt.Sprintf("%.3f", float32(p.numTradee)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", numTradee, specifier))
 
-	kast := fmt.Sprintf("%.3f", float32(p.kast)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", kast, specifier))
-
-	mvp := fmt.Sprintf("%.3f", float32(p.numMVP)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", mvp, specifier))
-
-	moneySaved := fmt.Sprintf("%.3f", float32(p.totalSavedMoney)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", moneySaved, specifier))
-
-	sniperKill := fmt.Sprintf("%.3f", float32(p.numKillSniperRifle)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", sniperKill, specifier))
-
-	meleeKill := fmt.Sprintf("%.3f", float32(p.numKillMelee)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", meleeKill, specifier))
-
-	shotgunKill := fmt.Sprintf("%.3f", float32(p.numKillShotgun)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", shotgunKill, specifier))
-
-	assultRKill := fmt.Sprintf("%.3f", float32(p.numKillAssultRifle)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", assultRKill, specifier))
-
-	pistolKill := fmt.Sprintf("%.3f", float32(p.numKillPistol)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", pistolKill, specifier))
-
-	machineGunKill := fmt.Sprintf("%.3f", float32(p.numKillMachineGun)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", machineGunKill, specifier))
-
-	smgKill := fmt.Sprintf("%.3f", float32(p.numKillSMG)/roundPlayedf)
-	sb.WriteString(fmt.Sprintf("%s%s", smgKill, specifier))
-
-	totalHit := float32(p.shotsHit)
-	headHit := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.numHitHead), totalHit))
-	sb.WriteString(fmt.Sprintf("%s%s", headHit, specifier))
-
-	stomachHit := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.numHitStomach), totalHit))
-	sb.WriteString(fmt.Sprintf("%s%s", stomachHit, specifier))
-
-	chestHit := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.numHitChest), totalHit))
-	sb.WriteString(fmt.Sprintf("%s%s", chestHit, specifier))
-
-	legsHit := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.numHitLegs), totalHit))
-	sb.WriteString(fmt.Sprintf("%s%s", legsHit, specifier))
-
-	armsHit := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.numHitArms), totalHit))
-	sb.WriteString(fmt.Sprintf("%s%s", armsHit, specifier))
-
-	unitDamageCost := fmt.Sprintf("%.3f", utils.SafeDivision(p.damageCost, float32(p.totalDmg)))
-	sb.WriteString(fmt.Sprintf("%s%s", unitDamageCost, specifier))
-
-	avarageKillDistance := fmt.Sprintf("%.3f", utils.SafeDivision(p.totalKillDistance, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", avarageKillDistance, specifier))
-
-	avaragePlayerSaved := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.savedFriends), roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", avaragePlayerSaved, specifier))
-
-	playerWonHealth := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.totalHealthWon), roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", playerWonHealth, specifier))
-
-	playerLostHealth := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.totalHealthLost), roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", playerLostHealth, specifier))
-
-	lastMemberSurvived := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.lastMemberSurvived), roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", lastMemberSurvived, specifier))
-
-	timeHurtToKill := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.timeHurtToKill.Seconds()), float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", timeHurtToKill, specifier))
-
-	spraySniper := fmt.Sprintf("%.3f", utils.SafeDivision(p.spraySniperRifle, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", spraySniper, specifier))
-
-	sprayShotgun := fmt.Sprintf("%.3f", utils.SafeDivision(p.sprayShotgun, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", sprayShotgun, specifier))
-
-	sprayARifle := fmt.Sprintf("%.3f", utils.SafeDivision(p.sprayAssultRifle, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", sprayARifle, specifier))
-
-	sprayPistol := fmt.Sprintf("%.3f", utils.SafeDivision(p.sprayPistol, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", sprayPistol, specifier))
-
-	sprayMachineGun := fmt.Sprintf("%.3f", utils.SafeDivision(p.sprayMachineGun, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", sprayMachineGun, specifier))
-
-	spraySMG := fmt.Sprintf("%.3f", utils.SafeDivision(p.spraySMG, float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", spraySMG, specifier))
+	return values
+}
 
-	roundWinPercentage := fmt.Sprintf("%.3f", p.roundWinPercentage)
-	sb.WriteString(fmt.Sprintf("%s%s", roundWinPercentage, specifier))
+// OutputPlayerState output as string form of given player feature values
+func OutputPlayerState(sb strings.Builder, steamID int64, name string, values []float32, Won int) strings.Builder {
+	playerName := strings.Replace(name, specifier, " ", -1)
+	sb.WriteString(fmt.Sprintf("%d%s%s%s", steamID, specifier, playerName, specifier))
 
-	roundWinTime := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.totalRoundWinTime.Seconds()), roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", roundWinTime, specifier))
+	for _, value := range values {
+		sb.WriteString(fmt.Sprintf("%s%s", fmt.Sprintf("%.3f", value), specifier))
+	}
 
-	duckKill := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.duckKill), float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", duckKill, specifier))
+	sb.WriteString(fmt.Sprintf("%s", fmt.Sprint(Won)))
 
-	memberDeathDistance := fmt.Sprintf("%.3f", utils.SafeDivision(p.totalMemberKilledDistance, roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", memberDeathDistance, specifier))
+	sb.WriteByte('\n')
 
-	sniperKilled := fmt.Sprintf("%.3f", utils.SafeDivision(float32(p.sniperKilled), float32(p.kill)))
-	sb.WriteString(fmt.Sprintf("%s%s", sniperKilled, specifier))
+	return sb
+}
 
-	occupiedArea := fmt.Sprintf("%.3f", utils.SafeDivision(p.teamOccupiedArea, roundPlayedf))
-	sb.WriteString(fmt.Sprintf("%s%s", occupiedArea, specifier))
+// EndRoundStats return statistics of the player since the last recorded round
+func (p *PPlayer) EndRoundStats() RoundStats {
+	current := roundCounters{kill: p.kill, death: p.death, assist: p.assist, totalDmg: p.totalDmg,
+		firstKill: p.firstKill, flashAssists: p.flashAssists, savedMoney: p.totalSavedMoney}
+	last := p.lastRoundCounters
+	p.lastRoundCounters = current
+
+	return RoundStats{
+		Kills:        current.kill - last.kill,
+		Deaths:       current.death - last.death,
+		Assists:      current.assist - last.assist,
+		Damage:       current.totalDmg - last.totalDmg,
+		FirstKill:    current.firstKill > last.firstKill,
+		FlashAssists: current.flashAssists - last.flashAssists,
+		MoneySaved:   current.savedMoney - last.savedMoney,
+	}
+}
 
-	sb.WriteString(fmt.Sprintf("%s", fmt.Sprint(Won)))
+// *** round staging ****
+
+// StageRound take a snapshot of the player state so that
+// the changes done in the current round can be discarded later
+func (p *PPlayer) StageRound() {
+	snapshot := *p
+	snapshot.viewHistory = nil
+	snapshot.roundSnapshot = nil
+	snapshot.lastHurt = make(map[int64]*HurtTuples, len(p.lastHurt))
+	for id, hurt := range p.lastHurt {
+		hurtCopy := *hurt
+		snapshot.lastHurt[id] = &hurtCopy
+	}
+	snapshot.spottedPlayers = make(map[int64]*SpottedPlayer, len(p.spottedPlayers))
+	for id, spotted := range p.spottedPlayers {
+		spottedCopy := *spotted
+		snapshot.spottedPlayers[id] = &spottedCopy
+	}
+	p.roundSnapshot = &snapshot
+}
 
-	sb.WriteByte('\n')
+// CommitRound keep the changes done since the round has been staged
+func (p *PPlayer) CommitRound() { p.roundSnapshot = nil }
 
-	return sb
+// DiscardRound roll back the changes done since the round has been staged
+func (p *PPlayer) DiscardRound() {
+	// player has been connected after the round is staged
+	if p.roundSnapshot == nil {
+		p.ResetPlayerState()
+		return
+	}
+	// parser player and connection related state is not a statistic
+	// of the round, so it is kept
+	parserPlayer, oldTeam, viewHistory := p.Player, p.oldTeam, p.viewHistory
+	*p = *p.roundSnapshot
+	p.Player, p.oldTeam, p.viewHistory = parserPlayer, oldTeam, viewHistory
 }
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	player "github.com/markus-wa/demoinfocs-golang/common"
+)
+
+// playRound change statistics of a player as an event handler would do in a round
+func playRound(p *PPlayer, tick int) {
+	p.kill++
+	p.NotifyDeath(tick)
+	p.NotifyAssist()
+	p.NotifyDamageTaken(40)
+	p.NotifyRoundParticipated()
+	p.lastHurt[2] = &HurtTuples{FirstHurtTick: tick, LastHurtTick: tick, RemaningHealth: 60}
+}
+
+// TestDiscardRound test that a discarded staged round leaves statistics of the player unchanged
+func TestDiscardRound(t *testing.T) {
+	parserPlayer := &player.Player{}
+	p := NewPPlayer(parserPlayer, nil)
+	playRound(p, 100)
+	p.CommitRound()
+	expected := p.FeatureValues()
+
+	p.StageRound()
+	playRound(p, 200)
+	p.lastHurt[2].RemaningHealth = 10
+	p.DiscardRound()
+
+	if actual := p.FeatureValues(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected features %v after discard, got %v", expected, actual)
+	}
+	if p.GetNumDeaths() != 1 || p.GetNumAssists() != 1 || p.GetRoundsParticipated() != 1 {
+		t.Errorf("expected statistics of a single round, got %d deaths, %d assists, %d rounds",
+			p.GetNumDeaths(), p.GetNumAssists(), p.GetRoundsParticipated())
+	}
+	// maps are copied, so changes of a discarded round do not leak into the snapshot
+	if hurt := p.lastHurt[2]; hurt == nil || hurt.LastHurtTick != 100 || hurt.RemaningHealth != 60 {
+		t.Errorf("expected hurt state of the first round, got %+v", hurt)
+	}
+	// parser player is not a statistic of the round
+	if p.Player != parserPlayer {
+		t.Error("expected parser player to be kept")
+	}
+}
+
+// TestCommitRound test that staged and committed rounds give the same statistics as rounds played without staging
+func TestCommitRound(t *testing.T) {
+	staged := NewPPlayer(&player.Player{}, nil)
+	unstaged := NewPPlayer(&player.Player{}, nil)
+	for _, tick := range []int{100, 200} {
+		staged.StageRound()
+		playRound(staged, tick)
+		staged.CommitRound()
+		playRound(unstaged, tick)
+	}
+	// a cancelled round does not change the result
+	staged.StageRound()
+	playRound(staged, 300)
+	staged.DiscardRound()
+
+	if !reflect.DeepEqual(staged.FeatureValues(), unstaged.FeatureValues()) {
+		t.Errorf("expected features %v, got %v", unstaged.FeatureValues(), staged.FeatureValues())
+	}
+	if !reflect.DeepEqual(staged.RawFeatureValues(2), unstaged.RawFeatureValues(2)) {
+		t.Errorf("expected raw features %v, got %v", unstaged.RawFeatureValues(2), staged.RawFeatureValues(2))
+	}
+}
+
+// TestDiscardRoundWithoutSnapshot test that a player connected after the round is staged is reset
+func TestDiscardRoundWithoutSnapshot(t *testing.T) {
+	p := NewPPlayer(&player.Player{}, nil)
+	playRound(p, 100)
+	p.DiscardRound()
+
+	if p.GetNumDeaths() != 0 || p.GetNumAssists() != 0 || p.GetRoundsParticipated() != 0 {
+		t.Errorf("expected reset statistics, got %d deaths, %d assists, %d rounds",
+			p.GetNumDeaths(), p.GetNumAssists(), p.GetRoundsParticipated())
+	}
+}
@@ -0,0 +1,246 @@
+// Package demoio package to open plain and compressed demo files
+package demoio
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Format format of a demo file detected by its magic bytes
+type Format int
+
+// formats of a demo file
+const (
+	// FormatUnknown not a known format, handled as plain demo
+	FormatUnknown Format = iota
+	FormatDemo
+	FormatGzip
+	FormatBzip2
+	FormatXz
+	FormatZip
+)
+
+const (
+	demoExt = ".dem"
+	// max length of magic bytes
+	magicLen = 8
+)
+
+// magic bytes of formats
+var magics = []struct {
+	format Format
+	magic  []byte
+}{
+	{FormatDemo, []byte("HL2DEMO\x00")},
+	{FormatGzip, []byte{0x1f, 0x8b}},
+	{FormatBzip2, []byte("BZh")},
+	{FormatXz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{FormatZip, []byte("PK\x03\x04")},
+}
+
+// known extensions of demo files and archives
+var knownExts = []string{demoExt, ".gz", ".bz2", ".xz", ".zip"}
+
+// String return name of the format
+func (f Format) String() string {
+	switch f {
+	case FormatDemo:
+		return "demo"
+	case FormatGzip:
+		return "gzip"
+	case FormatBzip2:
+		return "bzip2"
+	case FormatXz:
+		return "xz"
+	case FormatZip:
+		return "zip"
+	}
+
+	return "unknown"
+}
+
+// DetectFormat detect format of given header bytes
+func DetectFormat(header []byte) Format {
+	for _, m := range magics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format
+		}
+	}
+
+	return FormatUnknown
+}
+
+// IsDemoFile check whether file name has an extension of a demo file or an archive
+func IsDemoFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, knownExt := range knownExts {
+		if ext == knownExt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Entry a demo in a demo file. A plain or compressed demo file has
+// only one entry while a zip archive can have many.
+type Entry struct {
+	// name of the demo, for archives it includes the path inside the archive
+	Name string
+	// format of the file the entry is read from
+	Format Format
+	open   func() (io.ReadCloser, error)
+}
+
+// Open open decompressed demo stream of the entry
+func (e *Entry) Open() (io.ReadCloser, error) { return e.open() }
+
+// List list all demos in the file at given path
+func List(filePath string) ([]*Entry, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, magicLen)
+	n, err := io.ReadFull(f, header)
+	f.Close()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	format := DetectFormat(header[:n])
+
+	if format == FormatZip {
+		return listZip(filePath)
+	}
+
+	entry := &Entry{
+		Name:   demoName(filePath),
+		Format: format,
+		open: func() (io.ReadCloser, error) {
+			return openFile(filePath, format)
+		},
+	}
+
+	return []*Entry{entry}, nil
+}
+
+// openFile open a plain or compressed demo file
+func openFile(filePath string, format Format) (io.ReadCloser, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	switch format {
+	case FormatGzip:
+		r, err = gzip.NewReader(f)
+	case FormatBzip2:
+		r = bzip2.NewReader(bufio.NewReader(f))
+	case FormatXz:
+		r, err = xz.NewReader(bufio.NewReader(f))
+	default:
+		// plain demo, the file itself can be used
+		return f, nil
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &readCloser{Reader: r, closer: f}, nil
+}
+
+// listZip list all demos in a zip archive
+func listZip(filePath string) ([]*Entry, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var entries []*Entry
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), demoExt) {
+			continue
+		}
+		name := file.Name
+		entries = append(entries, &Entry{
+			Name:   name,
+			Format: FormatZip,
+			open: func() (io.ReadCloser, error) {
+				return openZipEntry(filePath, name)
+			},
+		})
+	}
+
+	return entries, nil
+}
+
+// openZipEntry open a demo inside a zip archive
+func openZipEntry(filePath, name string) (io.ReadCloser, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			archive.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: r, closer: multiCloser{r, archive}}, nil
+	}
+	archive.Close()
+
+	return nil, os.ErrNotExist
+}
+
+// demoName get demo name from file path by removing compression extensions
+func demoName(filePath string) string {
+	name := filepath.Base(filePath)
+	for _, ext := range []string{".gz", ".bz2", ".xz"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+
+	return name
+}
+
+// readCloser reader closing underlying file on close
+type readCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+// Close close underlying file
+func (rc *readCloser) Close() error { return rc.closer.Close() }
+
+// multiCloser close all closers in order
+type multiCloser []io.Closer
+
+// Close close all closers and return first error
+func (mc multiCloser) Close() error {
+	var firstErr error
+	for _, c := range mc {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
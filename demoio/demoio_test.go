@@ -0,0 +1,80 @@
+package demoio
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var demoContent = []byte("HL2DEMO\x00rest of the demo")
+
+// TestListCompressed test listing and reading of compressed demo files
+func TestListCompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "demoio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gzPath := filepath.Join(dir, "match.dem.gz")
+	gzFile, err := os.Create(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzWriter := gzip.NewWriter(gzFile)
+	gzWriter.Write(demoContent)
+	gzWriter.Close()
+	gzFile.Close()
+
+	zipPath := filepath.Join(dir, "matches.zip")
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipWriter := zip.NewWriter(zipFile)
+	for _, name := range []string{"m1.dem", "readme.txt", "maps/m2.dem"} {
+		w, _ := zipWriter.Create(name)
+		w.Write(demoContent)
+	}
+	zipWriter.Close()
+	zipFile.Close()
+
+	cases := []struct {
+		path   string
+		format Format
+		names  []string
+	}{
+		{gzPath, FormatGzip, []string{"match.dem"}},
+		{zipPath, FormatZip, []string{"m1.dem", "maps/m2.dem"}},
+	}
+
+	for _, c := range cases {
+		entries, err := List(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(c.names) {
+			t.Fatalf("%s: expected %d entries, got %d", c.path, len(c.names), len(entries))
+		}
+		for i, entry := range entries {
+			if entry.Name != c.names[i] || entry.Format != c.format {
+				t.Errorf("%s: unexpected entry %s (%s)", c.path, entry.Name, entry.Format)
+			}
+			r, err := entry.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if DetectFormat(content) != FormatDemo {
+				t.Errorf("%s: decompressed content is not a demo", entry.Name)
+			}
+		}
+	}
+}
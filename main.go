@@ -1,47 +1,343 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
+	metadata "github.com/markus-wa/demoinfocs-golang/metadata"
 	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	batch "github.com/quancore/demoanalyzer-go/batch"
+	demoio "github.com/quancore/demoanalyzer-go/demoio"
+	server "github.com/quancore/demoanalyzer-go/server"
+	// register parquet and sqlite output formats
+	_ "github.com/quancore/demoanalyzer-go/parquetsink"
+	_ "github.com/quancore/demoanalyzer-go/sqlitesink"
 	utils "github.com/quancore/demoanalyzer-go/utils"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+const (
+	// command to run analyzer as a http service
+	serveCommand = "serve"
+	// command to analyse demos as they land in a directory
+	watchCommand = "watch"
+)
+
 func init() {
-	utils.ReadConfFile()
+	exitOnError(utils.ReadConfFile())
 
 	pflag.String("demofilepath", "", "The path of demofile")
-	pflag.String("outpath", "", "The path of result text file")
+	pflag.String("outpath", "", "The path of result file, - for standard output")
 	pflag.String("logfilepath", "log.txt", "The path of result text file")
 	pflag.Bool("checkanalyzer", false, "Flag whether test analyser result when finished")
+	pflag.String("demodir", "", "The directory of demofiles to analyse in batch mode")
+	pflag.String("outdir", ".", "The directory of result text files in batch mode")
+	pflag.Int("workers", viper.GetInt("batch.concurrent_worker"), "The number of concurrent workers in batch mode")
+	pflag.String("manifest", viper.GetString("batch.manifest"), "The path of batch manifest (csv or json)")
+	pflag.Bool("singlepass", false, "Analyse demo in a single parsing")
+	pflag.Bool("roundtable", viper.GetBool("output.round_table"), "Write per round player table next to result files")
+	pflag.Bool("teamtable", viper.GetBool("output.team_table"), "Write per team table next to result files")
+	pflag.String("aliases", viper.GetString("output.alias_file"), "The path of player alias file mapping SteamIDs to canonical names")
+	pflag.Bool("matchinfo", viper.GetBool("output.match_metadata"), "Write match metadata next to result files")
+	pflag.Bool("roundledger", viper.GetBool("output.round_ledger"), "Write ledger of all detected rounds next to result files")
+	pflag.Bool("rawcounts", viper.GetBool("output.raw_counts"), "Add raw feature values and their denominators next to features")
+	pflag.Bool("timeline", viper.GetBool("output.timeline"), "Write event timeline of valid rounds next to result files")
+	pflag.String("format", viper.GetString("output.format"), "The format of result files (text, csv, json, jsonl, sqlite or parquet)")
+
+	pflag.String("addr", viper.GetString("serve.addr"), "The address to listen in serve mode")
 
 	pflag.Parse()
 	viper.BindPFlags(pflag.CommandLine)
+	viper.BindPFlag("parse.single_pass", pflag.Lookup("singlepass"))
+	viper.BindPFlag("output.format", pflag.Lookup("format"))
+	viper.BindPFlag("output.round_table", pflag.Lookup("roundtable"))
+	viper.BindPFlag("output.team_table", pflag.Lookup("teamtable"))
+	viper.BindPFlag("output.match_metadata", pflag.Lookup("matchinfo"))
+	viper.BindPFlag("output.round_ledger", pflag.Lookup("roundledger"))
+	viper.BindPFlag("output.timeline", pflag.Lookup("timeline"))
+	viper.BindPFlag("output.raw_counts", pflag.Lookup("rawcounts"))
+	viper.BindPFlag("output.alias_file", pflag.Lookup("aliases"))
 
+	if viper.GetString("demodir") != "" || pflag.Arg(0) == serveCommand || pflag.Arg(0) == watchCommand {
+		return
+	}
 	if _, err := os.Stat(viper.GetString("demofilepath")); err != nil {
-		panic(fmt.Sprintf("Failed to read test demo %q", viper.GetString("demofilepath")))
+		exitOnError(fmt.Errorf("failed to read demo %q: %v", viper.GetString("demofilepath"), err))
 	}
 }
 
 func main() {
-	// defer utils.RecoverPanic()
+	ctx := interruptContext()
+	if pflag.Arg(0) == serveCommand {
+		runServer(ctx)
+		return
+	}
+	if pflag.Arg(0) == watchCommand {
+		runWatch(ctx)
+		return
+	}
+	if demoDir := viper.GetString("demodir"); demoDir != "" {
+		runBatch(ctx, demoDir)
+		return
+	}
 
 	demoFilePath := viper.GetString("demofilepath")
 	outPath := viper.GetString("outpath")
 	logpath := viper.GetString("logfilepath")
+
+	entries, err := demoio.List(demoFilePath)
+	exitOnError(err)
+	if len(entries) == 0 {
+		exitOnError(fmt.Errorf("no demo file found in %q", demoFilePath))
+	}
+
+	aliases := loadAliases()
+	numFailed := 0
+	for _, entry := range entries {
+		entryOutPath, entryLogPath := outPath, logpath
+		// an archive can include many demos, so each demo gets its own files
+		if len(entries) > 1 {
+			if outPath != analyser.StdoutPath {
+				entryOutPath = entryPath(outPath, entry.Name)
+			}
+			entryLogPath = entryPath(logpath, entry.Name)
+		}
+		// a failed demo does not stop the other demos of an archive
+		if err := processEntry(ctx, demoFilePath, entry, entryOutPath, entryLogPath, aliases, len(entries) == 1); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", entry.Name, err)
+			numFailed++
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if numFailed > 0 {
+		os.Exit(1)
+	}
+}
+
+// processEntry analyse a single demo in a demo file and write its outputs
+func processEntry(ctx context.Context, demoFilePath string, entry *demoio.Entry, outPath, logpath string,
+	aliases map[int64]string, isPlotted bool) error {
+	isResultStdout := outPath == analyser.StdoutPath
+	result, err := analyseEntry(ctx, demoFilePath, entry, logpath, isResultStdout, aliases)
+	if err != nil {
+		return err
+	}
+	if err := analyser.WriteResult(viper.GetString("output.format"), outPath, result); err != nil {
+		return err
+	}
+	if isResultStdout {
+		return nil
+	}
+	if viper.GetBool("output.round_table") {
+		if err := analyser.WriteRoundTable(analyser.RoundTablePath(outPath), result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.team_table") {
+		if err := analyser.WriteTeamTable(analyser.TeamTablePath(outPath), result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.match_metadata") {
+		if err := analyser.WriteMatchMetadata(analyser.MatchMetadataPath(outPath), result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.round_ledger") {
+		if err := analyser.WriteRoundLedger(analyser.RoundLedgerPath(outPath), result); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool("output.timeline") {
+		if err := analyser.WriteTimeline(analyser.TimelinePath(outPath), result); err != nil {
+			return err
+		}
+	}
+
+	// plot kill positions if radar image of the map is available
+	if _, ok := metadata.MapNameToMap[result.Match.MapName]; ok && isPlotted {
+		if err := analyser.PrintHeatmap(result, analyser.HeatmapOut); err != nil {
+			return err
+		}
+		return analyser.ClusterPoints(result, analyser.ClusterOut)
+	}
+
+	return nil
+}
+
+// analyseEntry analyse a single demo in a demo file. If the result is written
+// to standard output, the log is not mirrored to the console. Analyser is
+// closed before returning, so its spool and log files are released.
+func analyseEntry(ctx context.Context, demoFilePath string, entry *demoio.Entry, logpath string, isResultStdout bool,
+	aliases map[int64]string) (*analyser.MatchResult, error) {
 	isMethodName := viper.GetBool("log.is_method_name")
+	// progress line would be interleaved with the log mirrored to the console
+	isStdout := viper.GetBool("log.stdout") && !isResultStdout
 
-	f, err := os.Open(demoFilePath)
+	f, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
-	utils.CheckError(err)
 
 	// initilize analyser
-	analyser := analyser.NewAnalyser(f, logpath, outPath, isMethodName, true)
+	demoAnalyser, err := analyser.NewAnalyser(f, logpath, isMethodName, isStdout)
+	if err != nil {
+		return nil, err
+	}
+	defer demoAnalyser.Close()
+	demoAnalyser.SetAliases(aliases)
+	if viper.GetBool("parse.ledger_cache") {
+		demoAnalyser.SetLedgerPath(analyser.LedgerPath(demoFilePath))
+	}
+	var entryName string
+	if entry.Format == demoio.FormatZip {
+		entryName = entry.Name
+	}
+	demoAnalyser.SetOverridePath(analyser.OverridePath(demoFilePath, entryName))
+	if !isStdout {
+		demoAnalyser.SetProgressHandler(printProgress(entry.Name))
+	}
 	// finally parse demofile
-	analyser.Analyze()
+	result, err := demoAnalyser.Analyze(ctx)
+	if !isStdout {
+		// end progress line
+		fmt.Fprintln(os.Stderr)
+	}
 
+	return result, err
+}
+
+// entryPath add demo name of an archive entry to a file path. Whole path of
+// the entry is used so that demos with the same name in different directories
+// of the archive do not overwrite each other.
+func entryPath(filePath, entryName string) string {
+	ext := filepath.Ext(filePath)
+	demoName := strings.Replace(strings.TrimSuffix(entryName, path.Ext(entryName)), "/", "_", -1)
+
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(filePath, ext), demoName, ext)
+}
+
+// loadAliases load the player alias file in the config once for all demos
+func loadAliases() map[int64]string {
+	aliasPath := viper.GetString("output.alias_file")
+	if aliasPath == "" {
+		return nil
+	}
+	aliases, err := analyser.LoadAliases(aliasPath)
+	exitOnError(err)
+
+	return aliases
+}
+
+// runBatch analyse all demofiles in a directory and write a manifest
+func runBatch(ctx context.Context, demoDir string) {
+	outDir := viper.GetString("outdir")
+	records, err := batch.Run(ctx, demoDir, outDir, viper.GetInt("workers"), loadAliases())
+	exitOnError(err)
+
+	manifestPath := viper.GetString("manifest")
+	if !filepath.IsAbs(manifestPath) {
+		manifestPath = filepath.Join(outDir, manifestPath)
+	}
+	exitOnError(batch.WriteManifest(manifestPath, records))
+
+	var numFailed int
+	for _, record := range records {
+		if !record.Success {
+			numFailed++
+		}
+	}
+	fmt.Printf("Analyzed %d demo files, %d failed. Manifest: %s\n", len(records), numFailed, manifestPath)
+}
+
+// runServer analyse uploaded demos over a REST API until interrupted
+func runServer(ctx context.Context) {
+	addr := viper.GetString("addr")
+	maxUpload := viper.GetInt64("serve.max_upload_mb") << 20
+	jobTTL := time.Duration(viper.GetFloat64("serve.job_ttl") * float64(time.Second))
+	demoServer := server.New(viper.GetInt("serve.queue_size"), viper.GetInt("serve.concurrent_worker"),
+		viper.GetString("serve.log_dir"), maxUpload, jobTTL)
+	demoServer.SetAliases(loadAliases())
+
+	fmt.Printf("Listening on %s\n", addr)
+	if err := demoServer.Serve(ctx, addr); err != http.ErrServerClosed {
+		exitOnError(err)
+	}
+}
+
+// runWatch analyse demos landing in a directory until interrupted
+func runWatch(ctx context.Context) {
+	demoDir, outDir := viper.GetString("demodir"), viper.GetString("outdir")
+	if demoDir == "" {
+		exitOnError(fmt.Errorf("--demodir is required in watch mode"))
+	}
+	statePath := viper.GetString("watch.state_file")
+	if !filepath.IsAbs(statePath) {
+		statePath = filepath.Join(outDir, statePath)
+	}
+
+	watcher := &batch.Watcher{
+		DemoDir:      demoDir,
+		OutDir:       outDir,
+		Workers:      viper.GetInt("workers"),
+		PollInterval: time.Duration(viper.GetFloat64("watch.poll_interval") * float64(time.Second)),
+		SettleTime:   time.Duration(viper.GetFloat64("watch.settle_time") * float64(time.Second)),
+		StatePath:    statePath,
+		Aliases:      loadAliases(),
+		Notify: func(demoPath string, records []*batch.Record) {
+			for _, record := range records {
+				if record.Success {
+					fmt.Printf("%s: analyzed, %s %d-%d\n", demoPath, record.MapName, record.TScore, record.CTScore)
+				} else {
+					fmt.Printf("%s: failed, %s\n", demoPath, record.Error)
+				}
+			}
+		},
+	}
+
+	fmt.Printf("Watching %s\n", demoDir)
+	exitOnError(watcher.Run(ctx))
+}
+
+// printProgress create a progress handler rendering the progress of an analyze as a single line
+func printProgress(name string) analyser.ProgressHandler {
+	return func(progress analyser.Progress) {
+		fmt.Fprintf(os.Stderr, "\r%s: pass %d %5.1f%% round %d", name, progress.Pass, progress.Fraction*100, progress.Round)
+	}
+}
+
+// interruptContext create a context cancelled on interrupt signal
+// so that running analyze stops cleanly. A second interrupt is not
+// handled anymore, so it exits immediately.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	return ctx
+}
+
+// exitOnError print error and exit with failure status if an error occured
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
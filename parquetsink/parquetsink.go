@@ -0,0 +1,162 @@
+// Package parquetsink package to write player features of match results as
+// a partitioned Apache Parquet dataset. Importing the package registers the
+// "parquet" output format.
+package parquetsink
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	common "github.com/quancore/demoanalyzer-go/common"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	// Format name of the output format
+	Format = "parquet"
+	// file extension of a match file in the dataset
+	fileExt = ".parquet"
+	// number of goroutines marshalling rows
+	numMarshaller = 1
+)
+
+func init() {
+	// a dataset is a directory, so it has no extension
+	analyser.RegisterSink(Format, "", true, func(path string) (analyser.OutputSink, error) {
+		return Open(path)
+	})
+}
+
+// Sink output sink writing player features of each match into its own
+// file under a directory partitioned by map, <dir>/map=<map>/<match id>.parquet.
+// Map is only stored in the partition key, so it is not duplicated in the files.
+// Files of a match already in the dataset are replaced.
+type Sink struct {
+	dir string
+}
+
+// Open create a sink writing into given dataset directory
+func Open(dir string) (*Sink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Sink{dir: dir}, nil
+}
+
+// schema create parquet schema of player rows. Raw counts are
+// integer columns while feature values are float columns.
+func schema(featureNames []string, counts map[string]bool) []string {
+	md := []string{
+		"name=match_id, type=BYTE_ARRAY, convertedtype=UTF8",
+		"name=steam_id, type=INT64",
+		"name=name, type=BYTE_ARRAY, convertedtype=UTF8",
+		"name=team, type=BYTE_ARRAY, convertedtype=UTF8",
+		"name=side, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY",
+		"name=rounds_participated, type=INT32",
+		"name=round_played, type=INT32",
+	}
+	for _, name := range featureNames {
+		if counts[name] {
+			md = append(md, fmt.Sprintf("name=%s, type=INT64", name))
+		} else {
+			md = append(md, fmt.Sprintf("name=%s, type=FLOAT", name))
+		}
+	}
+
+	return append(md, "name=won, type=INT32")
+}
+
+// Write write player rows of a match result to its partition
+func (s *Sink) Write(result *analyser.MatchResult) error {
+	match := result.Match
+	if match.ID == "" {
+		return fmt.Errorf("match result has no id")
+	}
+	mapName := match.MapAlias
+	if mapName == "" {
+		mapName = "unknown"
+	}
+	partition := filepath.Join(s.dir, "map="+strings.Replace(mapName, string(filepath.Separator), "_", -1))
+	if err := os.MkdirAll(partition, 0755); err != nil {
+		return err
+	}
+
+	// file is written under a temp name so that a reader never sees a partial file
+	tmpFile, err := ioutil.TempFile(partition, ".*"+fileExt)
+	if err != nil {
+		return err
+	}
+	if err := writeRows(tmpFile, result); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	// map alias of a re-analysed match can be changed
+	if err := s.removeMatch(match.ID, partition); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filepath.Join(partition, match.ID+fileExt))
+}
+
+// removeMatch remove file of a match from partitions other than given partition
+func (s *Sink) removeMatch(matchID, partition string) error {
+	dirs, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(s.dir, dir.Name())
+		if !dir.IsDir() || !strings.HasPrefix(dir.Name(), "map=") || path == partition {
+			continue
+		}
+		if err := os.Remove(filepath.Join(path, matchID+fileExt)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeRows write a row for each player of a match result
+func writeRows(f *os.File, result *analyser.MatchResult) error {
+	counts := common.CountColumns()
+	pw, err := writer.NewCSVWriterFromWriter(schema(result.FeatureNames, counts), f, numMarshaller)
+	if err != nil {
+		return err
+	}
+
+	match := result.Match
+	for _, player := range result.Players {
+		row := []interface{}{match.ID, player.SteamID, player.Name, player.Team, player.Side,
+			int32(player.RoundsPlayed), int32(match.RoundPlayed)}
+		for i, value := range player.Features {
+			if counts[result.FeatureNames[i]] {
+				row = append(row, int64(math.Round(float64(value))))
+			} else {
+				row = append(row, value)
+			}
+		}
+		row = append(row, int32(player.Won))
+		if err := pw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return pw.WriteStop()
+}
+
+// Close do nothing, each match file is closed after it is written
+func (s *Sink) Close() error { return nil }
@@ -0,0 +1,99 @@
+package parquetsink
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// TestWrite test that a re-analysed match is moved to the partition of its new map
+// and raw counts are read back as integer columns while ratios are float columns
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parquetsink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	result := &analyser.MatchResult{
+		Match:        analyser.MatchInfo{ID: "hash", MapAlias: "de_train", RoundPlayed: 24},
+		FeatureNames: []string{"FPR", "Match_Rounds_Played"},
+		Players: []analyser.PlayerResult{
+			{SteamID: 1, Name: "a", Team: "A", Side: "T", Won: 1, RoundsPlayed: 24, Features: []float32{0.5, 23.6}},
+		},
+	}
+	reanalysed := *result
+	reanalysed.Match.MapAlias = "de_nuke"
+
+	sink, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*analyser.MatchResult{result, &reanalysed} {
+		if err := sink.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "map=de_train", "hash"+fileExt)); !os.IsNotExist(err) {
+		t.Errorf("expected match to be removed from its previous partition, got %v", err)
+	}
+	path := filepath.Join(dir, "map=de_nuke", "hash"+fileExt)
+	f, err := local.NewLocalFileReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pr, err := reader.NewParquetColumnReader(f, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+	if pr.GetNumRows() != 1 {
+		t.Fatalf("expected 1 row, got %d", pr.GetNumRows())
+	}
+
+	expected := map[string]struct {
+		columnType parquet.Type
+		value      interface{}
+	}{
+		"steam_id":            {parquet.Type_INT64, int64(1)},
+		"rounds_participated": {parquet.Type_INT32, int32(24)},
+		"FPR":                 {parquet.Type_FLOAT, float32(0.5)},
+		"Match_Rounds_Played": {parquet.Type_INT64, int64(24)},
+		"won":                 {parquet.Type_INT32, int32(1)},
+	}
+	// first schema element is the root, others are columns in order. Reader
+	// renames columns, names written to the file are kept in schema infos.
+	for i, element := range pr.Footer.Schema[1:] {
+		name := pr.SchemaHandler.Infos[i+1].ExName
+		column, ok := expected[name]
+		if !ok {
+			continue
+		}
+		delete(expected, name)
+		if element.GetType() != column.columnType {
+			t.Errorf("column %s: expected type %v, got %v", name, column.columnType, element.GetType())
+			continue
+		}
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != 1 || values[0] != column.value {
+			t.Errorf("column %s: expected %v, got %v", name, column.value, values)
+		}
+	}
+	for name := range expected {
+		t.Errorf("column %s is missing", name)
+	}
+}
@@ -0,0 +1,407 @@
+// Package server package to analyse uploaded demos over a REST API
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	demoio "github.com/quancore/demoanalyzer-go/demoio"
+	"github.com/spf13/viper"
+)
+
+// JobStatus status of an analyze job
+type JobStatus string
+
+// statuses of a job
+const (
+	StatusQueued  JobStatus = "queued"
+	StatusRunning JobStatus = "running"
+	StatusDone    JobStatus = "done"
+	StatusFailed  JobStatus = "failed"
+)
+
+const (
+	// form field of the demo in a multipart upload
+	demoField = "demo"
+	// timeout for running requests on shutdown
+	shutdownTimeout = 10 * time.Second
+	// period of evicting expired jobs
+	evictInterval = time.Minute
+)
+
+// Job analyze of an uploaded demo
+type Job struct {
+	ID     string    `json:"id"`
+	Status JobStatus `json:"status"`
+	// failure reason, empty if job is not failed
+	Error    string            `json:"error,omitempty"`
+	Progress analyser.Progress `json:"progress"`
+	// path of the uploaded demo, removed after the analyze
+	demoPath string
+	result   *analyser.MatchResult
+	// time the job is done or failed
+	finished time.Time
+}
+
+// Server http server running analyze jobs on a bounded queue
+type Server struct {
+	queue   chan *Job
+	workers int
+	// directory of job log files
+	logDir string
+	// max size of an uploaded demo in bytes
+	maxUpload int64
+	// duration a finished job is kept with its result and log file,
+	// finished jobs are kept forever if it is not positive
+	jobTTL time.Duration
+	// canonical names of players by their SteamID64
+	aliases map[int64]string
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	// running workers
+	wg sync.WaitGroup
+}
+
+// New create a server with a queue of given size and given number of workers.
+// Finished jobs are evicted after jobTTL.
+func New(queueSize, workers int, logDir string, maxUpload int64, jobTTL time.Duration) *Server {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Server{
+		queue:     make(chan *Job, queueSize),
+		workers:   workers,
+		logDir:    logDir,
+		maxUpload: maxUpload,
+		jobTTL:    jobTTL,
+		jobs:      make(map[string]*Job),
+	}
+}
+
+// SetAliases set canonical names of players used in all jobs
+func (s *Server) SetAliases(aliases map[int64]string) { s.aliases = aliases }
+
+// Handler get http handler of the REST API
+//
+//	POST /jobs               upload a demo, returns the queued job
+//	GET  /jobs/{id}          status and progress of a job
+//	GET  /jobs/{id}/result   match result of a finished job
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+
+	return mux
+}
+
+// Serve start workers and listen given address until the context is cancelled.
+// On shutdown, demos of queued jobs and log files of all jobs are removed.
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	if err := os.MkdirAll(s.logDir, 0755); err != nil {
+		return err
+	}
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.work(ctx)
+		}()
+	}
+	go s.evict(ctx)
+
+	httpServer := &http.Server{Addr: addr, Handler: s.Handler()}
+	errs := make(chan error, 1)
+	go func() { errs <- httpServer.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := httpServer.Shutdown(shutdownCtx)
+		s.wg.Wait()
+		s.cleanup()
+		return err
+	}
+}
+
+// evict evict expired jobs periodically until the context is cancelled
+func (s *Server) evict(ctx context.Context) {
+	if s.jobTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.evictJobs(now)
+		}
+	}
+}
+
+// evictJobs remove jobs finished before job ttl with their log files
+func (s *Server) evictJobs(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, job := range s.jobs {
+		if job.finished.IsZero() || now.Sub(job.finished) < s.jobTTL {
+			continue
+		}
+		delete(s.jobs, id)
+		os.Remove(s.logPath(id))
+	}
+}
+
+// cleanup remove demos of queued jobs and log files of all jobs,
+// workers have to be stopped before
+func (s *Server) cleanup() {
+	for len(s.queue) > 0 {
+		job := <-s.queue
+		os.Remove(job.demoPath)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.jobs {
+		os.Remove(s.logPath(id))
+	}
+	s.jobs = make(map[string]*Job)
+}
+
+// logPath get path of the log file of a job
+func (s *Server) logPath(id string) string { return filepath.Join(s.logDir, id+".log") }
+
+// handleJobs handle job creation
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "only POST is allowed")
+		return
+	}
+
+	demoPath, err := s.saveUpload(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		os.Remove(demoPath)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	job := &Job{ID: id, Status: StatusQueued, demoPath: demoPath}
+
+	// queue is bounded, a full queue rejects the job
+	select {
+	case s.queue <- job:
+	default:
+		os.Remove(demoPath)
+		writeError(w, http.StatusServiceUnavailable, "analyze queue is full")
+		return
+	}
+	s.mu.Lock()
+	s.jobs[id] = job
+	snapshot := *job
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// handleJob handle status and result requests of a job
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is allowed")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	if len(parts) > 2 || (len(parts) == 2 && parts[1] != "result") {
+		writeError(w, http.StatusNotFound, "unknown path")
+		return
+	}
+
+	s.mu.Lock()
+	job, ok := s.jobs[parts[0]]
+	var snapshot Job
+	if ok {
+		snapshot = *job
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown job")
+		return
+	}
+
+	if len(parts) == 1 {
+		writeJSON(w, http.StatusOK, snapshot)
+		return
+	}
+
+	switch snapshot.Status {
+	case StatusDone:
+		writeJSON(w, http.StatusOK, snapshot.result)
+	case StatusFailed:
+		writeError(w, http.StatusUnprocessableEntity, snapshot.Error)
+	default:
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s", snapshot.Status))
+	}
+}
+
+// saveUpload save uploaded demo to a temp file and return its path.
+// The demo is either the request body or the demo field of a multipart form.
+func (s *Server) saveUpload(w http.ResponseWriter, r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	var body io.Reader = r.Body
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return "", err
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return "", fmt.Errorf("no %q field in form", demoField)
+			} else if err != nil {
+				return "", err
+			}
+			if part.FormName() == demoField {
+				body = part
+				break
+			}
+		}
+	}
+
+	f, err := ioutil.TempFile("", "demoanalyzer-upload-*")
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n == 0 {
+		err = errors.New("empty demo")
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// work run queued jobs until the context is cancelled
+func (s *Server) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.run(ctx, job)
+		}
+	}
+}
+
+// run analyse the demo of a job and record its result
+func (s *Server) run(ctx context.Context, job *Job) {
+	defer os.Remove(job.demoPath)
+	s.mu.Lock()
+	job.Status = StatusRunning
+	s.mu.Unlock()
+
+	result, err := s.analyse(ctx, job)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.finished = time.Now()
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = StatusDone
+	job.result = result
+}
+
+// analyse analyse the demo of a job
+func (s *Server) analyse(ctx context.Context, job *Job) (result *analyser.MatchResult, err error) {
+	// a broken demo file can panic inside the parser,
+	// it should only fail the current job
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	entries, err := demoio.List(job.demoPath)
+	if err != nil {
+		return nil, err
+	} else if len(entries) != 1 {
+		return nil, fmt.Errorf("expected a single demo, found %d", len(entries))
+	}
+
+	f, err := entries[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	demoAnalyser, err := analyser.NewAnalyser(f, s.logPath(job.ID), viper.GetBool("log.is_method_name"), false)
+	if err != nil {
+		return nil, err
+	}
+	defer demoAnalyser.Close()
+	demoAnalyser.SetAliases(s.aliases)
+	demoAnalyser.SetProgressHandler(func(progress analyser.Progress) {
+		s.mu.Lock()
+		job.Progress = progress
+		s.mu.Unlock()
+	})
+
+	return demoAnalyser.Analyze(ctx)
+}
+
+// newJobID create a random job id
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// writeJSON write a json response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError write a json error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
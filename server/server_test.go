@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestQueue test job creation, status request and bounded queue
+func TestQueue(t *testing.T) {
+	// workers are not started so uploaded demos stay in the queue
+	s := New(1, 1, os.TempDir(), 1<<20, time.Hour)
+	handler := s.Handler()
+	defer func() {
+		for _, job := range s.jobs {
+			os.Remove(job.demoPath)
+		}
+	}()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty upload: expected %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec := post("HL2DEMO\x00")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("upload: expected %d, got %d", http.StatusAccepted, rec.Code)
+	}
+	var job Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusQueued {
+		t.Errorf("expected queued job, got %s", job.Status)
+	}
+
+	if rec := post("HL2DEMO\x00"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("full queue: expected %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/jobs/" + job.ID, http.StatusOK},
+		{"/jobs/" + job.ID + "/result", http.StatusConflict},
+		{"/jobs/unknown", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rec.Code != c.code {
+			t.Errorf("%s: expected %d, got %d", c.path, c.code, rec.Code)
+		}
+	}
+}
+
+// TestEvictJobs test that only jobs finished before job ttl are evicted with their log files
+func TestEvictJobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	s := New(1, 1, dir, 1<<20, time.Hour)
+	s.jobs = map[string]*Job{
+		"expired": {ID: "expired", Status: StatusDone, finished: now.Add(-2 * time.Hour)},
+		"failed":  {ID: "failed", Status: StatusFailed, finished: now.Add(-time.Minute)},
+		"running": {ID: "running", Status: StatusRunning},
+	}
+	for id := range s.jobs {
+		if err := ioutil.WriteFile(s.logPath(id), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.evictJobs(now)
+	if _, ok := s.jobs["expired"]; ok {
+		t.Error("expired job has not been evicted")
+	}
+	if _, err := os.Stat(s.logPath("expired")); !os.IsNotExist(err) {
+		t.Error("log file of expired job has not been removed")
+	}
+	for _, id := range []string{"failed", "running"} {
+		if _, ok := s.jobs[id]; !ok {
+			t.Errorf("%s job has been evicted", id)
+		}
+		if _, err := os.Stat(s.logPath(id)); err != nil {
+			t.Errorf("log file of %s job: %v", id, err)
+		}
+	}
+}
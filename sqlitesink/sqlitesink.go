@@ -0,0 +1,346 @@
+// Package sqlitesink package to write match results into a SQLite database.
+// Importing the package registers the "sqlite" output format.
+package sqlitesink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	common "github.com/quancore/demoanalyzer-go/common"
+	// pure go sqlite driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// Format name of the output format
+	Format = "sqlite"
+	// Ext file extension of the database
+	Ext = ".sqlite"
+	// milliseconds to wait for the database lock of another writer
+	busyTimeout = 30000
+)
+
+func init() {
+	analyser.RegisterSink(Format, Ext, true, func(path string) (analyser.OutputSink, error) {
+		return Open(path)
+	})
+}
+
+// schema of tables except player feature columns
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS matches (
+		id TEXT PRIMARY KEY,
+		analyzer_version TEXT,
+		server_name TEXT,
+		client_name TEXT,
+		tick_rate REAL,
+		playback_time REAL,
+		map_name TEXT,
+		map_alias TEXT,
+		round_played INTEGER,
+		t_score INTEGER,
+		ct_score INTEGER,
+		winner_team TEXT,
+		round_winners TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS rounds (
+		match_id TEXT REFERENCES matches(id),
+		number INTEGER,
+		start_tick INTEGER,
+		end_tick INTEGER,
+		official_end_tick INTEGER,
+		t_score INTEGER,
+		ct_score INTEGER,
+		winner TEXT,
+		PRIMARY KEY (match_id, number)
+	)`,
+	`CREATE TABLE IF NOT EXISTS round_players (
+		match_id TEXT REFERENCES matches(id),
+		round INTEGER,
+		steam_id INTEGER,
+		name TEXT,
+		side TEXT,
+		round_type TEXT,
+		kills INTEGER,
+		deaths INTEGER,
+		assists INTEGER,
+		damage INTEGER,
+		first_kill INTEGER,
+		flash_assists INTEGER,
+		money_saved INTEGER,
+		kast INTEGER,
+		survived INTEGER,
+		winner TEXT,
+		winner_team TEXT,
+		PRIMARY KEY (match_id, round, steam_id, name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS match_teams (
+		match_id TEXT REFERENCES matches(id),
+		name TEXT,
+		side TEXT,
+		score INTEGER,
+		t_rounds_won INTEGER,
+		ct_rounds_won INTEGER,
+		pistol_rounds_won INTEGER,
+		eco_rounds_won INTEGER,
+		force_buy_rounds_won INTEGER,
+		damage INTEGER,
+		first_kills INTEGER,
+		clutches_won INTEGER,
+		trades INTEGER,
+		av_occupied_area REAL,
+		won INTEGER,
+		PRIMARY KEY (match_id, side)
+	)`,
+	`CREATE TABLE IF NOT EXISTS players (
+		steam_id INTEGER PRIMARY KEY,
+		name TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS player_match_features (
+		match_id TEXT REFERENCES matches(id),
+		steam_id INTEGER,
+		name TEXT,
+		team TEXT,
+		side TEXT,
+		won INTEGER,
+		rounds_participated INTEGER,
+		PRIMARY KEY (match_id, steam_id, name)
+	)`,
+}
+
+// column added to a table created by an older version
+type column struct {
+	name    string
+	colType string
+}
+
+// columns of matches table added after the table has been created
+var matchColumns = []column{
+	{"server_name", "TEXT"},
+	{"client_name", "TEXT"},
+	{"tick_rate", "REAL"},
+	{"playback_time", "REAL"},
+}
+
+// columns of player_match_features table added after the table has been created
+var playerColumns = []column{
+	{"rounds_participated", "INTEGER"},
+}
+
+// Sink output sink writing match results into a SQLite database.
+// Rows of a match already in the database are replaced.
+type Sink struct {
+	db *sql.DB
+}
+
+// Open open or create a SQLite database at path
+func Open(path string) (*Sink, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)", path, busyTimeout))
+	if err != nil {
+		return nil, err
+	}
+	sink := &Sink{db: db}
+	if err := sink.migrate(common.FeatureNames()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return sink, nil
+}
+
+// migrate create tables and add columns missing in the database. Schema is
+// changed in an immediate transaction, so that concurrent writers of the
+// same database wait for each other instead of adding the same column twice.
+func (s *Sink) migrate(featureNames []string) error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return err
+	}
+	if err := migrateSchema(ctx, conn, featureNames); err != nil {
+		conn.ExecContext(ctx, `ROLLBACK`)
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `COMMIT`)
+
+	return err
+}
+
+// migrateSchema create tables and add columns missing in the database
+func migrateSchema(ctx context.Context, conn *sql.Conn, featureNames []string) error {
+	for _, stmt := range schema {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if err := addColumns(ctx, conn, "matches", matchColumns); err != nil {
+		return err
+	}
+	if err := addColumns(ctx, conn, "player_match_features", playerColumns); err != nil {
+		return err
+	}
+
+	var featureColumns []column
+	for _, name := range featureNames {
+		featureColumns = append(featureColumns, column{name, "REAL"})
+	}
+
+	return addColumns(ctx, conn, "player_match_features", featureColumns)
+}
+
+// addColumns add columns missing in a table
+func addColumns(ctx context.Context, conn *sql.Conn, table string, columns []column) error {
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, quote(col.name), col.colType)
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write upsert a match result in a single transaction
+func (s *Sink) Write(result *analyser.MatchResult) error {
+	match := result.Match
+	if match.ID == "" {
+		return fmt.Errorf("match result has no id")
+	}
+	// raw count columns are only added when a result has them
+	if err := s.migrate(result.FeatureNames); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := writeResult(tx, result); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// writeResult write all rows of a match result by replacing old rows of the match
+func writeResult(tx *sql.Tx, result *analyser.MatchResult) error {
+	match, metadata := result.Match, result.Metadata
+	_, err := tx.Exec(`INSERT INTO matches (id, analyzer_version, server_name, client_name, tick_rate, playback_time,
+		map_name, map_alias, round_played, t_score, ct_score, winner_team, round_winners)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET analyzer_version = excluded.analyzer_version, server_name = excluded.server_name,
+		client_name = excluded.client_name, tick_rate = excluded.tick_rate, playback_time = excluded.playback_time,
+		map_name = excluded.map_name, map_alias = excluded.map_alias, round_played = excluded.round_played,
+		t_score = excluded.t_score, ct_score = excluded.ct_score, winner_team = excluded.winner_team,
+		round_winners = excluded.round_winners`,
+		match.ID, match.AnalyzerVersion, metadata.ServerName, metadata.ClientName, metadata.TickRate, metadata.PlaybackTime,
+		match.MapName, match.MapAlias, match.RoundPlayed, match.TScore, match.CTScore, match.WinnerTeam, match.RoundWinners)
+	if err != nil {
+		return err
+	}
+
+	// rounds and players of a re-analysed match can differ, so old rows are removed
+	for _, table := range []string{"rounds", "round_players", "match_teams", "player_match_features"} {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE match_id = ?`, table), match.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, round := range result.Rounds {
+		_, err := tx.Exec(`INSERT INTO rounds (match_id, number, start_tick, end_tick, official_end_tick, t_score, ct_score, winner)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			match.ID, round.Number, round.StartTick, round.EndTick, round.OfficialEndTick, round.TScore, round.CTScore, round.Winner)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, row := range result.RoundPlayers {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO round_players (match_id, round, steam_id, name, side, round_type, kills, deaths,
+			assists, damage, first_kill, flash_assists, money_saved, kast, survived, winner, winner_team)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			match.ID, row.Round, row.SteamID, row.Name, row.Side, row.RoundType, row.Kills, row.Deaths,
+			row.Assists, row.Damage, row.FirstKill, row.FlashAssists, row.MoneySaved, row.KAST, row.Survived, row.Winner, row.WinnerTeam)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, team := range result.Teams {
+		_, err := tx.Exec(`INSERT INTO match_teams (match_id, name, side, score, t_rounds_won, ct_rounds_won, pistol_rounds_won,
+			eco_rounds_won, force_buy_rounds_won, damage, first_kills, clutches_won, trades, av_occupied_area, won)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			match.ID, team.Name, team.Side, team.Score, team.TRoundsWon, team.CTRoundsWon, team.PistolRoundsWon,
+			team.EcoRoundsWon, team.ForceBuyRoundsWon, team.Damage, team.FirstKills, team.ClutchesWon, team.Trades,
+			float64(team.AvOccupiedArea), team.Won)
+		if err != nil {
+			return err
+		}
+	}
+
+	columns := []string{"match_id", "steam_id", "name", "team", "side", "won", "rounds_participated"}
+	for _, name := range result.FeatureNames {
+		columns = append(columns, quote(name))
+	}
+	insertFeatures := fmt.Sprintf(`INSERT OR REPLACE INTO player_match_features (%s) VALUES (%s)`,
+		strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+	for _, player := range result.Players {
+		// bots do not have a steam id
+		if player.SteamID != 0 {
+			_, err := tx.Exec(`INSERT INTO players (steam_id, name) VALUES (?, ?)
+				ON CONFLICT(steam_id) DO UPDATE SET name = excluded.name`, player.SteamID, player.Name)
+			if err != nil {
+				return err
+			}
+		}
+
+		values := []interface{}{match.ID, player.SteamID, player.Name, player.Team, player.Side, player.Won, player.RoundsPlayed}
+		for _, value := range player.Features {
+			values = append(values, float64(value))
+		}
+		if _, err := tx.Exec(insertFeatures, values...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close close the database
+func (s *Sink) Close() error { return s.db.Close() }
+
+// quote quote an identifier
+func quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,112 @@
+package sqlitesink
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	analyser "github.com/quancore/demoanalyzer-go/analyser"
+)
+
+// TestUpsert test that writing a re-analysed match replaces its rows
+func TestUpsert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlitesink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "matches"+Ext)
+
+	result := &analyser.MatchResult{
+		Match:        analyser.MatchInfo{ID: "hash", MapName: "de_train", TScore: 16, CTScore: 10},
+		Metadata:     analyser.MatchMetadata{ServerName: "server", TickRate: 128},
+		Rounds:       []analyser.RoundResult{{Number: 1}, {Number: 2}},
+		FeatureNames: []string{"FPR"},
+		Players: []analyser.PlayerResult{
+			{SteamID: 1, Name: "a", RoundsPlayed: 24, Features: []float32{1}},
+			{SteamID: 2, Name: "b", Features: []float32{0.5}},
+		},
+	}
+	reanalysed := *result
+	reanalysed.Match.TScore = 16
+	reanalysed.Match.CTScore = 14
+	reanalysed.Rounds = []analyser.RoundResult{{Number: 1}}
+	reanalysed.Players = result.Players[:1]
+
+	for _, r := range []*analyser.MatchResult{result, &reanalysed} {
+		sink, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		sink.Close()
+	}
+
+	sink, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	cases := []struct {
+		query    string
+		expected int
+	}{
+		{`SELECT COUNT(*) FROM matches`, 1},
+		{`SELECT ct_score FROM matches WHERE id = 'hash'`, 14},
+		{`SELECT tick_rate FROM matches WHERE id = 'hash'`, 128},
+		{`SELECT COUNT(*) FROM rounds`, 1},
+		{`SELECT COUNT(*) FROM player_match_features`, 1},
+		{`SELECT rounds_participated FROM player_match_features WHERE steam_id = 1`, 24},
+		{`SELECT COUNT(*) FROM players`, 2},
+	}
+	for _, c := range cases {
+		var value int
+		if err := sink.db.QueryRow(c.query).Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		if value != c.expected {
+			t.Errorf("%s: expected %d, got %d", c.query, c.expected, value)
+		}
+	}
+}
+
+// TestConcurrentMigrate test that workers adding the same columns to a new database do not fail
+func TestConcurrentMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlitesink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "matches"+Ext)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sink, err := Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer sink.Close()
+			errs <- sink.Write(&analyser.MatchResult{
+				Match:        analyser.MatchInfo{ID: string(rune('a' + i))},
+				FeatureNames: []string{"FPR", "FPR_Raw", "Rounds_Played"},
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
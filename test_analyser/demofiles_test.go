@@ -1,6 +1,7 @@
 package testAnalyser
 
 import (
+	"context"
 	"fmt"
 	"go/build"
 	"io/ioutil"
@@ -12,6 +13,7 @@ import (
 	"testing"
 
 	analyser "github.com/quancore/demoanalyzer-go/analyser"
+	batch "github.com/quancore/demoanalyzer-go/batch"
 	utils "github.com/quancore/demoanalyzer-go/utils"
 	logging "github.com/sirupsen/logrus"
 
@@ -28,7 +30,9 @@ var stdout bool
 var log *logging.Logger
 
 func init() {
-	utils.ReadConfFile()
+	if err := utils.ReadConfFile(); err != nil {
+		panic(err)
+	}
 
 	// get conf variables
 	demofilePath = viper.GetString("test.demofile_path")
@@ -42,7 +46,11 @@ func init() {
 	viper.Set("checkanalyzer", true)
 
 	// init test logger
-	log = utils.InitLogger("test_log.txt", logLevel, isMethodName, true)
+	var err error
+	log, _, err = utils.InitLogger("test_log.txt", logLevel, isMethodName, true)
+	if err != nil {
+		panic(err)
+	}
 
 	log.Info("Logger has been initilized")
 
@@ -66,20 +74,20 @@ func TestDemofiles(t *testing.T) {
 
 	log.Info(fmt.Sprintf("Found files number: %d", len(files)))
 
-	var tasks []*Task
+	var tasks []*batch.Task
 
 	for _, file := range files {
 		if !file.IsDir() && filepath.Ext(file.Name()) == ".dem" {
 			filename := file.Name()
 			filepath := filepath.Join(demofilePath, filename)
-			tasks = append(tasks, NewTask(func() error {
+			tasks = append(tasks, batch.NewTask(func() error {
 				err := analyseDemofile(filename, filepath, t)
 				return err
 			}))
 		}
 	}
 
-	p := NewPool(tasks, numConcurrentWorker)
+	p := batch.NewPool(tasks, numConcurrentWorker)
 	p.Run()
 
 }
@@ -101,13 +109,28 @@ func analyseDemofile(filename, filepath string, t *testing.T) error {
 	f, err := os.Open(filepath)
 	if err != nil {
 		t.Error(err)
+		return err
 	}
 	defer f.Close()
 
 	// initilize analyser
-	analyser := analyser.NewAnalyser(f, logFilePath, outputPath, isMethodName, stdout)
+	demoAnalyser, err := analyser.NewAnalyser(f, logFilePath, isMethodName, stdout)
+	if err != nil {
+		t.Error(err)
+		return err
+	}
+	defer demoAnalyser.Close()
 	// finally parse demofile
-	analyser.Analyze()
+	result, err := demoAnalyser.Analyze(context.Background())
+	if err != nil {
+		t.Error(err)
+		return err
+	}
+
+	err = analyser.WriteMatchResult(outputPath, result)
+	if err != nil {
+		t.Error(err)
+	}
 
 	return err
 
@@ -15,13 +15,6 @@ import (
 
 const float32EqualityThreshold = 1e-6
 
-// CheckError check whether an error occured
-func CheckError(err error) {
-	if err != nil {
-		log.Panic(err)
-	}
-}
-
 // GetGoPath get current go path in the system
 func GetGoPath() string {
 	gopath := os.Getenv("GOPATH")
@@ -60,8 +53,9 @@ func GetGoPath() string {
 // 	log.Info("Logger has been initilized.")
 // }
 
-// InitLogger setup logger for both printing file and console
-func InitLogger(path, logLevel string, methodname bool, isstdout bool) *log.Logger {
+// InitLogger setup logger for both printing file and console.
+// Returned closer closes the log file, it has to be closed when the logger is not used anymore.
+func InitLogger(path, logLevel string, methodname bool, isstdout bool) (*log.Logger, io.Closer, error) {
 	// remove old file if exist
 	os.Remove(path)
 
@@ -75,8 +69,9 @@ func InitLogger(path, logLevel string, methodname bool, isstdout bool) *log.Logg
 	// open logging file
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
 	// check error
-	CheckError(err)
-	// defer f.Close()
+	if err != nil {
+		return nil, nil, err
+	}
 	wrt := io.Writer(f)
 
 	if isstdout {
@@ -99,18 +94,17 @@ func InitLogger(path, logLevel string, methodname bool, isstdout bool) *log.Logg
 		"level": level,
 	}).Info("Logger has been initilized")
 
-	return logger
+	return logger, f, nil
 }
 
 // ReadConfFile set config file variables and read it
 // it uses TOML format for config file
-func ReadConfFile() {
+func ReadConfFile() error {
 	viper.SetConfigName("config") // no need to include file extension
 	viper.AddConfigPath("../")    // set the path of your config file
 	viper.AddConfigPath(".")      // set the path of your config file
 
-	err := viper.ReadInConfig()
-	CheckError(err)
+	return viper.ReadInConfig()
 }
 
 // ###########################################3